@@ -0,0 +1,221 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 21.40
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/audit
+ */
+
+// Package audit records who changed what. Entries are written inside the caller's
+// transaction, so an audit row exists exactly when the change it describes does.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/db/postgres"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/observability/logging/ctxmeta"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultTable is the table used when Config.Table is empty.
+const DefaultTable = "audit_log"
+
+// SystemActor is recorded when the context carries no actor.
+const SystemActor = "system"
+
+// Action is what happened to an entity.
+type Action string
+
+// Common actions. Any other value is stored as is.
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+const tableDDL = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+	id          UUID        PRIMARY KEY,
+	occurred_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	actor       TEXT        NOT NULL,
+	tenant_id   TEXT        NOT NULL DEFAULT '',
+	trace_id    TEXT        NOT NULL DEFAULT '',
+	action      TEXT        NOT NULL,
+	entity_type TEXT        NOT NULL,
+	entity_id   TEXT        NOT NULL,
+	changes     JSONB       NOT NULL
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (entity_type, entity_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS %[3]s ON %[1]s (tenant_id, occurred_at DESC);`
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that records actor on audit entries, e.g. a user
+// ID or an API key name.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor carried by ctx, or SystemActor.
+func Actor(ctx context.Context) string {
+	if v, ok := ctx.Value(actorKey{}).(string); ok && v != "" {
+		return v
+	}
+	return SystemActor
+}
+
+// Entry is one recorded change.
+type Entry struct {
+	ID         string    `db:"id" json:"id"`
+	OccurredAt time.Time `db:"occurred_at" json:"occurred_at"`
+	Actor      string    `db:"actor" json:"actor"`
+	TenantID   string    `db:"tenant_id" json:"tenant_id,omitempty"`
+	TraceID    string    `db:"trace_id" json:"trace_id,omitempty"`
+	Action     Action    `db:"action" json:"action"`
+	EntityType string    `db:"entity_type" json:"entity_type"`
+	EntityID   string    `db:"entity_id" json:"entity_id"`
+	Changes    Changes   `db:"changes" json:"changes"`
+}
+
+// EventID implements event.Event.
+func (e *Entry) EventID() string { return e.ID }
+
+// EventName implements event.Event, e.g. "audit.device.update".
+func (e *Entry) EventName() string {
+	return "audit." + e.EntityType + "." + string(e.Action)
+}
+
+// EventKey implements event.Event. Entries of one entity share a key so they stay ordered.
+func (e *Entry) EventKey() string { return e.EntityType + ":" + e.EntityID }
+
+// Change describes a change to record. Before is nil for creates and After is nil
+// for deletes; both are compared through their JSON encoding.
+type Change struct {
+	Action     Action
+	EntityType string
+	EntityID   string
+	Before     any
+	After      any
+}
+
+// Config holds the configuration for a Recorder.
+type Config struct {
+	Table string // default DefaultTable
+	// Redact lists JSON fields whose values are replaced in the diff, e.g. secrets
+	// or key hashes. The field is still reported as changed.
+	Redact []string
+	// Outbox, when set, also queues every entry for publishing on Topic in the
+	// same transaction.
+	Outbox *postgres.Outbox
+	Topic  string
+}
+
+// Recorder writes audit entries.
+type Recorder struct {
+	cfg Config
+}
+
+// NewRecorder returns a Recorder for cfg.
+func NewRecorder(cfg Config) *Recorder {
+	if cfg.Table == "" {
+		cfg.Table = DefaultTable
+	}
+	return &Recorder{cfg: cfg}
+}
+
+func (r *Recorder) ident() string {
+	return pgx.Identifier{r.cfg.Table}.Sanitize()
+}
+
+// CreateTable creates the audit table and its indexes if they do not exist.
+func (r *Recorder) CreateTable(ctx context.Context, q postgres.Querier) error {
+	_, err := q.Exec(postgres.WithoutTenant(ctx), fmt.Sprintf(tableDDL,
+		r.ident(),
+		pgx.Identifier{r.cfg.Table + "_entity_idx"}.Sanitize(),
+		pgx.Identifier{r.cfg.Table + "_tenant_idx"}.Sanitize(),
+	))
+	return err
+}
+
+// Record diffs c and writes an entry through q, which should be the transaction
+// that applies the change. Actor, tenant and trace ID are taken from ctx. An update
+// that changes nothing is not recorded and returns a nil entry.
+func (r *Recorder) Record(ctx context.Context, q postgres.Querier, c Change) (*Entry, error) {
+	changes, err := Diff(c.Before, c.After, r.cfg.Redact...)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 && c.Action == ActionUpdate {
+		return nil, nil
+	}
+
+	tenant, _ := postgres.TenantID(ctx)
+	entry := &Entry{
+		ID:         uuid.NewString(),
+		OccurredAt: time.Now().UTC(),
+		Actor:      Actor(ctx),
+		TenantID:   tenant,
+		TraceID:    ctxmeta.TraceID(ctx),
+		Action:     c.Action,
+		EntityType: c.EntityType,
+		EntityID:   c.EntityID,
+		Changes:    changes,
+	}
+
+	payload, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return nil, fmt.Errorf("audit: marshal changes: %w", err)
+	}
+
+	_, err = q.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, occurred_at, actor, tenant_id, trace_id, action, entity_type, entity_id, changes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, r.ident()),
+		entry.ID, entry.OccurredAt, entry.Actor, entry.TenantID, entry.TraceID,
+		string(entry.Action), entry.EntityType, entry.EntityID, payload,
+	)
+	if err != nil {
+		return nil, postgres.Classify(err)
+	}
+
+	if r.cfg.Outbox != nil {
+		if err := r.cfg.Outbox.AddWith(ctx, q, r.cfg.Topic, entry, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+// Publisher sends audit entries straight to a message producer. Prefer
+// Config.Outbox when entries must not be published for rolled back changes.
+type Publisher struct {
+	Producer producer.MessageProducer
+	Topic    string
+}
+
+// Publish sends each entry as JSON, keyed by entity.
+func (p *Publisher) Publish(ctx context.Context, entries ...*Entry) error {
+	for _, e := range entries {
+		msg, err := producer.EventMessage(p.Topic, e)
+		if err != nil {
+			return fmt.Errorf("audit: marshal entry: %w", err)
+		}
+		if err := p.Producer.Send(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
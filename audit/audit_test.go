@@ -0,0 +1,227 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 22.25
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/audit
+ */
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/db/postgres"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/observability/logging/ctxmeta"
+	"github.com/sirupsen/logrus"
+)
+
+type apiKey struct {
+	Name    string   `json:"name"`
+	Hash    string   `json:"hash"`
+	Scopes  []string `json:"scopes"`
+	Revoked bool     `json:"revoked,omitempty"`
+}
+
+var _ event.Event = (*Entry)(nil)
+
+func TestDiff_Update(t *testing.T) {
+	before := apiKey{Name: "ci", Hash: "old", Scopes: []string{"send"}}
+	after := apiKey{Name: "ci", Hash: "new", Scopes: []string{"send", "read"}, Revoked: true}
+
+	changes, err := Diff(before, after, "hash")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changed fields, got %v", changes)
+	}
+	if _, ok := changes["name"]; ok {
+		t.Error("Expected unchanged field to be omitted")
+	}
+	if string(changes["hash"].Before) != string(Redacted) || string(changes["hash"].After) != string(Redacted) {
+		t.Errorf("Expected hash to be redacted, got %+v", changes["hash"])
+	}
+	if string(changes["scopes"].After) != `["send","read"]` {
+		t.Errorf("Expected scopes after value, got %s", changes["scopes"].After)
+	}
+	if changes["revoked"].Before != nil || string(changes["revoked"].After) != "true" {
+		t.Errorf("Expected revoked to be added, got %+v", changes["revoked"])
+	}
+}
+
+func TestDiff_CreateAndDelete(t *testing.T) {
+	key := &apiKey{Name: "ci", Hash: "h"}
+
+	created, err := Diff(nil, key)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(created) != 3 || created["name"].Before != nil {
+		t.Errorf("Expected every field to be added, got %v", created)
+	}
+
+	deleted, err := Diff(key, (*apiKey)(nil))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(deleted) != 3 || deleted["name"].After != nil {
+		t.Errorf("Expected every field to be removed, got %v", deleted)
+	}
+}
+
+func TestDiff_NotAnObject(t *testing.T) {
+	if _, err := Diff("a", "b"); err == nil {
+		t.Error("Expected error for non-object values")
+	}
+}
+
+func TestActor(t *testing.T) {
+	ctx := context.Background()
+	if got := Actor(ctx); got != SystemActor {
+		t.Errorf("Expected %s, got %s", SystemActor, got)
+	}
+	if got := Actor(WithActor(ctx, "user:42")); got != "user:42" {
+		t.Errorf("Expected user:42, got %s", got)
+	}
+}
+
+func TestEntry_Event(t *testing.T) {
+	e := &Entry{ID: "abc", Action: ActionUpdate, EntityType: "device", EntityID: "628123"}
+
+	if e.EventName() != "audit.device.update" {
+		t.Errorf("Expected audit.device.update, got %s", e.EventName())
+	}
+	if e.EventKey() != "device:628123" {
+		t.Errorf("Expected device:628123, got %s", e.EventKey())
+	}
+}
+
+func TestFilter_Query(t *testing.T) {
+	r := NewRecorder(Config{})
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	plan, err := r.keysetQuery().Build(Filter{EntityType: "device", Actor: "user:42", Since: since}.pageRequest())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, want := range []string{`FROM "audit_log"`, "actor = $", "entity_type = $", "occurred_at >= $", "ORDER BY occurred_at DESC, id DESC", "LIMIT 51"} {
+		if !strings.Contains(plan.SQL, want) {
+			t.Errorf("Expected SQL to contain %q, got %s", want, plan.SQL)
+		}
+	}
+	if len(plan.Args) != 3 {
+		t.Errorf("Expected 3 args, got %v", plan.Args)
+	}
+}
+
+type recordingProducer struct {
+	sent []producer.Message
+}
+
+func (p *recordingProducer) Send(_ context.Context, msg producer.Message) error {
+	p.sent = append(p.sent, msg)
+	return nil
+}
+
+func (p *recordingProducer) Flush(int) int { return 0 }
+func (p *recordingProducer) Close() error  { return nil }
+
+func TestPublisher_Publish(t *testing.T) {
+	rp := &recordingProducer{}
+	pub := &Publisher{Producer: rp, Topic: "audit"}
+
+	e := &Entry{ID: "abc", Action: ActionDelete, EntityType: "api_key", EntityID: "k1"}
+	if err := pub.Publish(context.Background(), e); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(rp.sent) != 1 || string(rp.sent[0].Key) != "api_key:k1" {
+		t.Fatalf("Expected one message keyed api_key:k1, got %v", rp.sent)
+	}
+	var got Entry
+	if err := json.Unmarshal(rp.sent[0].Value, &got); err != nil || got.ID != "abc" {
+		t.Errorf("Expected entry JSON, got %s (%v)", rp.sent[0].Value, err)
+	}
+	if name := rp.sent[0].Headers[producer.HeaderEventName]; name != "audit.api_key.delete" {
+		t.Errorf("Expected event name header audit.api_key.delete, got %q", name)
+	}
+}
+
+func TestRecorder_Integration(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database test: TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := postgres.NewDatabase(ctx, logrus.New(), postgres.Config{DSN: dsn, MaxConns: 2, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create database pool: %v", err)
+	}
+	defer pool.Close()
+
+	r := NewRecorder(Config{Table: "audit_log_test"})
+	if err := r.CreateTable(ctx, pool); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer func() {
+		_, _ = pool.Exec(ctx, `DROP TABLE audit_log_test`) //nolint:errcheck
+	}()
+
+	ctx = ctxmeta.WithTraceID(WithActor(ctx, "user:42"), "trace-1")
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	for i := range 3 {
+		_, err := r.Record(ctx, tx, Change{
+			Action:     ActionUpdate,
+			EntityType: "device",
+			EntityID:   "628123",
+			Before:     map[string]int{"n": i},
+			After:      map[string]int{"n": i + 1},
+		})
+		if err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	page, err := r.Query(ctx, pool, Filter{EntityType: "device", Limit: 2})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(page.Entries) != 2 || !page.HasMore {
+		t.Fatalf("Expected 2 entries and more, got %d (%v)", len(page.Entries), page.HasMore)
+	}
+	if page.Entries[0].Actor != "user:42" || page.Entries[0].TraceID != "trace-1" {
+		t.Errorf("Expected actor and trace from context, got %+v", page.Entries[0])
+	}
+
+	next, err := r.Query(ctx, pool, Filter{EntityType: "device", Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("Failed to query next page: %v", err)
+	}
+	if len(next.Entries) != 1 || next.HasMore {
+		t.Errorf("Expected 1 remaining entry, got %d", len(next.Entries))
+	}
+}
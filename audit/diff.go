@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 21.55
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/audit
+ */
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Redacted replaces the value of redacted fields in a diff.
+var Redacted = json.RawMessage(`"[REDACTED]"`)
+
+// FieldChange holds the JSON values of one field before and after a change.
+// A missing side means the field did not exist.
+type FieldChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Changes maps top-level JSON field names to their change.
+type Changes map[string]FieldChange
+
+// Diff compares the JSON encodings of before and after field by field. Either may
+// be nil. Nested objects are compared as a whole.
+func Diff(before, after any, redact ...string) (Changes, error) {
+	b, err := fieldsOf(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fieldsOf(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := Changes{}
+	for _, m := range []map[string]json.RawMessage{b, a} {
+		for k := range m {
+			if _, done := changes[k]; done {
+				continue
+			}
+
+			bv, av := b[k], a[k]
+			if bytes.Equal(bv, av) {
+				continue
+			}
+
+			if slices.Contains(redact, k) {
+				if bv != nil {
+					bv = Redacted
+				}
+				if av != nil {
+					av = Redacted
+				}
+			}
+			changes[k] = FieldChange{Before: bv, After: av}
+		}
+	}
+
+	return changes, nil
+}
+
+func fieldsOf(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("audit: marshal %T: %w", v, err)
+	}
+	if bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("audit: %T is not a JSON object", v)
+	}
+
+	// Compact so equal values compare equal regardless of formatting.
+	for k, val := range fields {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, val); err == nil {
+			fields[k] = buf.Bytes()
+		}
+	}
+	return fields, nil
+}
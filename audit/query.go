@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 22.10
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/audit
+ */
+
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/db/postgres"
+)
+
+// Filter selects audit entries. Empty fields match everything.
+type Filter struct {
+	Actor      string
+	TenantID   string
+	TraceID    string
+	Action     Action
+	EntityType string
+	EntityID   string
+	Since      time.Time // inclusive
+	Until      time.Time // exclusive
+
+	Limit  int    // default 50, at most 500
+	Cursor string // NextCursor of the previous page
+}
+
+// Page is one page of entries, newest first.
+type Page struct {
+	Entries    []*Entry
+	NextCursor string
+	HasMore    bool
+}
+
+func (r *Recorder) keysetQuery() postgres.KeysetQuery {
+	return postgres.KeysetQuery{
+		Select: "SELECT id, occurred_at, actor, tenant_id, trace_id, action, entity_type, entity_id, changes FROM " + r.ident(),
+		Sortable: map[string]string{
+			"occurred_at": "occurred_at",
+		},
+		Filterable: map[string]postgres.FilterSpec{
+			"actor":       {Column: "actor"},
+			"tenant_id":   {Column: "tenant_id"},
+			"trace_id":    {Column: "trace_id"},
+			"action":      {Column: "action"},
+			"entity_type": {Column: "entity_type"},
+			"entity_id":   {Column: "entity_id"},
+			"since":       {Column: "occurred_at", Ops: []postgres.FilterOp{postgres.OpGte}},
+			"until":       {Column: "occurred_at", Ops: []postgres.FilterOp{postgres.OpLt}},
+		},
+		TieBreaker:   "id",
+		DefaultSort:  []postgres.SortField{{Key: "occurred_at", Desc: true}},
+		DefaultLimit: 50,
+		MaxLimit:     500,
+	}
+}
+
+func (f Filter) pageRequest() postgres.PageRequest {
+	req := postgres.PageRequest{Limit: f.Limit, Cursor: f.Cursor}
+
+	eq := func(key, value string) {
+		if value != "" {
+			req.Filters = append(req.Filters, postgres.Filter{Key: key, Op: postgres.OpEq, Value: value})
+		}
+	}
+	eq("actor", f.Actor)
+	eq("tenant_id", f.TenantID)
+	eq("trace_id", f.TraceID)
+	eq("action", string(f.Action))
+	eq("entity_type", f.EntityType)
+	eq("entity_id", f.EntityID)
+
+	if !f.Since.IsZero() {
+		req.Filters = append(req.Filters, postgres.Filter{Key: "since", Op: postgres.OpGte, Value: f.Since.Format(time.RFC3339Nano)})
+	}
+	if !f.Until.IsZero() {
+		req.Filters = append(req.Filters, postgres.Filter{Key: "until", Op: postgres.OpLt, Value: f.Until.Format(time.RFC3339Nano)})
+	}
+
+	return req
+}
+
+// Query returns the entries matching f, newest first. On a tenant-scoped pool the
+// results are further limited by row-level security.
+func (r *Recorder) Query(ctx context.Context, q postgres.Querier, f Filter) (*Page, error) {
+	plan, err := r.keysetQuery().Build(f.pageRequest())
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := postgres.QueryAll[Entry](ctx, q, plan.SQL, plan.Args...)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, len(rows))
+	for i := range rows {
+		entries[i] = &rows[i]
+	}
+
+	page := &Page{}
+	page.Entries, page.HasMore = postgres.TrimPage(entries, plan.Limit)
+	if page.HasMore {
+		last := page.Entries[len(page.Entries)-1]
+		if page.NextCursor, err = plan.NextCursor(last.OccurredAt, last.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
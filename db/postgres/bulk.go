@@ -0,0 +1,358 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 16.42
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const defaultUpsertChunkSize = 500
+
+type structField struct {
+	column string
+	index  []int
+}
+
+var structFieldCache sync.Map // reflect.Type -> []structField
+
+// structFields maps the exported fields of t to columns. Columns come from the db
+// tag; untagged fields use the snake_case field name and `db:"-"` skips a field.
+func structFields(t reflect.Type) ([]structField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("postgres: %s is not a struct", t)
+	}
+
+	if v, ok := structFieldCache.Load(t); ok {
+		return v.([]structField), nil //nolint:errcheck
+	}
+
+	var fields []structField
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := range t.NumField() {
+			f := t.Field(i)
+			idx := append(slices.Clone(index), i)
+
+			tag, hasTag := f.Tag.Lookup("db")
+			if tag == "-" {
+				continue
+			}
+
+			if f.Anonymous && !hasTag && f.Type.Kind() == reflect.Struct {
+				walk(f.Type, idx)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+
+			column := strings.Split(tag, ",")[0]
+			if column == "" {
+				column = snakeCase(f.Name)
+			}
+			fields = append(fields, structField{column: column, index: idx})
+		}
+	}
+	walk(t, nil)
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("postgres: %s has no mapped fields", t)
+	}
+
+	structFieldCache.Store(t, fields)
+	return fields, nil
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func columnsOf(fields []structField) []string {
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		cols[i] = f.column
+	}
+	return cols
+}
+
+func valuesOf(v reflect.Value, fields []structField) []any {
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	values := make([]any, len(fields))
+	for i, f := range fields {
+		values[i] = v.FieldByIndex(f.index).Interface()
+	}
+	return values
+}
+
+func tableIdentifier(table string) pgx.Identifier {
+	return pgx.Identifier(strings.Split(table, "."))
+}
+
+func fieldsFor[T any]() ([]structField, error) {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return structFields(t)
+}
+
+// CopyFrom streams rows into table with the COPY protocol. Columns are taken from
+// the db tags of T, which may be a struct or a pointer to one. table may be
+// schema-qualified ("contacts.numbers").
+func CopyFrom[T any](ctx context.Context, db Querier, table string, rows iter.Seq[T]) (int64, error) {
+	fields, err := fieldsFor[T]()
+	if err != nil {
+		return 0, err
+	}
+
+	next, stop := iter.Pull(rows)
+	defer stop()
+
+	src := pgx.CopyFromFunc(func() ([]any, error) {
+		row, ok := next()
+		if !ok {
+			return nil, nil
+		}
+		return valuesOf(reflect.ValueOf(row), fields), nil
+	})
+
+	return db.CopyFrom(ctx, tableIdentifier(table), columnsOf(fields), src)
+}
+
+// UpsertOptions controls Upsert.
+type UpsertOptions struct {
+	// ConflictColumns is the ON CONFLICT target and is required.
+	ConflictColumns []string
+	// UpdateColumns are overwritten on conflict. Defaults to every non-conflict column.
+	UpdateColumns []string
+	// DoNothing keeps existing rows untouched on conflict.
+	DoNothing bool
+	// ChunkSize is the number of rows sent per batch, default 500.
+	ChunkSize int
+}
+
+// RowError reports a row rejected by Upsert. Index is the row's position in the input.
+type RowError struct {
+	Index int
+	Err   error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Index, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// UpsertResult summarizes an Upsert.
+type UpsertResult struct {
+	Rows     int   // rows read from the input
+	Affected int64 // rows inserted or updated
+	Errors   []RowError
+}
+
+func buildUpsertSQL(table string, columns []string, opts UpsertOptions) (string, error) {
+	if len(opts.ConflictColumns) == 0 {
+		return "", errors.New("postgres: upsert requires ConflictColumns")
+	}
+
+	quote := func(cols []string) []string {
+		out := make([]string, len(cols))
+		for i, c := range cols {
+			out[i] = pgx.Identifier{c}.Sanitize()
+		}
+		return out
+	}
+
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) ",
+		tableIdentifier(table).Sanitize(),
+		strings.Join(quote(columns), ", "),
+		strings.Join(placeholders, ", "),
+		strings.Join(quote(opts.ConflictColumns), ", "),
+	)
+
+	update := opts.UpdateColumns
+	if len(update) == 0 {
+		for _, c := range columns {
+			if !slices.Contains(opts.ConflictColumns, c) {
+				update = append(update, c)
+			}
+		}
+	}
+
+	if opts.DoNothing || len(update) == 0 {
+		return sql + "DO NOTHING", nil
+	}
+
+	sets := make([]string, len(update))
+	for i, c := range quote(update) {
+		sets[i] = c + " = EXCLUDED." + c
+	}
+
+	return sql + "DO UPDATE SET " + strings.Join(sets, ", "), nil
+}
+
+// Upsert runs INSERT ... ON CONFLICT for every row, sending ChunkSize rows per
+// pgx.Batch. Each chunk runs in its own transaction (a savepoint when db is a
+// transaction). When a chunk fails on the data of a row it is replayed row by row,
+// so valid rows are still written and rejected rows are reported in
+// UpsertResult.Errors. Any other failure, such as a lost connection or a
+// canceled ctx, stops the upsert and is returned.
+func Upsert[T any](ctx context.Context, db Querier, table string, rows iter.Seq[T], opts UpsertOptions) (UpsertResult, error) {
+	var result UpsertResult
+
+	fields, err := fieldsFor[T]()
+	if err != nil {
+		return result, err
+	}
+
+	sql, err := buildUpsertSQL(table, columnsOf(fields), opts)
+	if err != nil {
+		return result, err
+	}
+
+	size := opts.ChunkSize
+	if size <= 0 {
+		size = defaultUpsertChunkSize
+	}
+
+	chunk := make([][]any, 0, size)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		offset := result.Rows - len(chunk)
+		err := upsertChunk(ctx, db, sql, chunk, offset, &result)
+		chunk = chunk[:0]
+		return err
+	}
+
+	for row := range rows {
+		chunk = append(chunk, valuesOf(reflect.ValueOf(row), fields))
+		result.Rows++
+		if len(chunk) == size {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	return result, flush()
+}
+
+func upsertChunk(ctx context.Context, db Querier, sql string, chunk [][]any, offset int, result *UpsertResult) error {
+	affected, err := sendUpsertBatch(ctx, db, sql, chunk)
+	if err == nil {
+		result.Affected += affected
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if !isDataError(err) {
+		return err
+	}
+
+	for i, args := range chunk {
+		n, err := sendUpsertBatch(ctx, db, sql, [][]any{args})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !isDataError(err) {
+				return err
+			}
+			result.Errors = append(result.Errors, RowError{Index: offset + i, Err: Classify(err)})
+			continue
+		}
+		result.Affected += n
+	}
+
+	return nil
+}
+
+// isDataError reports whether err was caused by the data of a row: SQLSTATE class
+// 22 (data exception) or 23 (integrity constraint violation). Other errors, such
+// as a lost connection, would fail every row and stop the upsert instead.
+func isDataError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")
+}
+
+func sendUpsertBatch(ctx context.Context, db Querier, sql string, rows [][]any) (int64, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck
+	}()
+
+	batch := &pgx.Batch{}
+	for _, args := range rows {
+		batch.Queue(sql, args...)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	var affected int64
+	for range rows {
+		tag, err := br.Exec()
+		if err != nil {
+			_ = br.Close() //nolint:errcheck
+			return 0, err
+		}
+		affected += tag.RowsAffected()
+	}
+	if err := br.Close(); err != nil {
+		return 0, err
+	}
+
+	return affected, tx.Commit(ctx)
+}
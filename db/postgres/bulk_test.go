@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 17.20
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+)
+
+type auditFields struct {
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type contact struct {
+	auditFields
+	TenantID   string `db:"tenant_id"`
+	Phone      string `db:"phone"`
+	Name       string
+	WebhookURL string
+	internal   string //nolint:unused
+	Ignored    string `db:"-"`
+}
+
+func TestStructFields(t *testing.T) {
+	fields, err := structFields(reflect.TypeFor[contact]())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"created_at", "tenant_id", "phone", "name", "webhook_url"}
+	if got := columnsOf(fields); !slices.Equal(got, expected) {
+		t.Errorf("Expected columns %v, got %v", expected, got)
+	}
+
+	c := contact{TenantID: "t1", Phone: "628123", Name: "Budi"}
+	values := valuesOf(reflect.ValueOf(&c), fields)
+	if values[1] != "t1" || values[2] != "628123" || values[3] != "Budi" {
+		t.Errorf("Unexpected values %v", values)
+	}
+}
+
+func TestStructFields_NotStruct(t *testing.T) {
+	if _, err := structFields(reflect.TypeFor[string]()); err == nil {
+		t.Error("Expected error for non-struct type")
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Name":      "name",
+		"TenantID":  "tenant_id",
+		"HTTPURL":   "httpurl",
+		"DeviceJID": "device_jid",
+		"CreatedAt": "created_at",
+	}
+
+	for in, expected := range tests {
+		if got := snakeCase(in); got != expected {
+			t.Errorf("snakeCase(%q): expected %q, got %q", in, expected, got)
+		}
+	}
+}
+
+func TestBuildUpsertSQL(t *testing.T) {
+	cols := []string{"tenant_id", "phone", "name"}
+
+	sql, err := buildUpsertSQL("contacts", cols, UpsertOptions{ConflictColumns: []string{"tenant_id", "phone"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := `INSERT INTO "contacts" ("tenant_id", "phone", "name") VALUES ($1, $2, $3) ON CONFLICT ("tenant_id", "phone") DO UPDATE SET "name" = EXCLUDED."name"`
+	if sql != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, sql)
+	}
+
+	sql, err = buildUpsertSQL("crm.contacts", cols, UpsertOptions{ConflictColumns: []string{"phone"}, DoNothing: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected = `INSERT INTO "crm"."contacts" ("tenant_id", "phone", "name") VALUES ($1, $2, $3) ON CONFLICT ("phone") DO NOTHING`
+	if sql != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, sql)
+	}
+
+	if _, err := buildUpsertSQL("contacts", cols, UpsertOptions{}); err == nil {
+		t.Error("Expected error without ConflictColumns")
+	}
+}
+
+type phoneRow struct {
+	Phone string `db:"phone"`
+	Name  string `db:"name"`
+}
+
+// downQuerier is a Querier whose database is unreachable.
+type downQuerier struct {
+	Querier
+	begins int
+}
+
+func (q *downQuerier) Begin(context.Context) (pgx.Tx, error) {
+	q.begins++
+	return nil, errors.New("dial tcp: connection refused")
+}
+
+func TestUpsert_StopsOnInfrastructureError(t *testing.T) {
+	q := &downQuerier{}
+	rows := slices.Values([]contact{{Phone: "1"}, {Phone: "2"}, {Phone: "3"}})
+
+	result, err := Upsert(context.Background(), q, "contacts", rows, UpsertOptions{ConflictColumns: []string{"phone"}})
+	if err == nil {
+		t.Fatal("Expected the connection error to be returned")
+	}
+	if len(result.Errors) != 0 || q.begins != 1 {
+		t.Errorf("Expected no row-by-row replay, got %d row errors after %d attempts", len(result.Errors), q.begins)
+	}
+}
+
+func TestIsDataError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "23505"}, true},
+		{fmt.Errorf("row: %w", &pgconn.PgError{Code: "22001"}), true},
+		{&pgconn.PgError{Code: "57P01"}, false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := isDataError(tt.err); got != tt.want {
+			t.Errorf("isDataError(%v): expected %v, got %v", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestBulk_Integration(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database test: TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := NewDatabase(ctx, logrus.New(), Config{DSN: dsn, MaxConns: 2, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create database pool: %v", err)
+	}
+	defer pool.Close()
+
+	if _, err := pool.Exec(ctx, `CREATE TABLE bulk_test (phone TEXT PRIMARY KEY CHECK (phone <> ''), name TEXT)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer func() {
+		_, _ = pool.Exec(ctx, "DROP TABLE bulk_test") //nolint:errcheck
+	}()
+
+	n, err := CopyFrom(ctx, pool, "bulk_test", slices.Values([]phoneRow{{"1", "a"}, {"2", "b"}}))
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 copied rows, got %d (err: %v)", n, err)
+	}
+
+	rows := []phoneRow{{"2", "b2"}, {"", "invalid"}, {"3", "c"}}
+	res, err := Upsert(ctx, pool, "bulk_test", slices.Values(rows), UpsertOptions{ConflictColumns: []string{"phone"}, ChunkSize: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if res.Rows != 3 || res.Affected != 2 {
+		t.Errorf("Expected 3 rows and 2 affected, got %+v", res)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Index != 1 || !errors.Is(res.Errors[0], ErrCheckViolation) {
+		t.Errorf("Expected check violation on row 1, got %v", res.Errors)
+	}
+}
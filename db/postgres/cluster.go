@@ -0,0 +1,302 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 09.05
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultReplicaCheckInterval = 5 * time.Second
+
+// replicationLagQuery returns the replay lag in seconds, or 0 when the replica
+// has replayed everything it received (an idle primary does not count as lag).
+const replicationLagQuery = `
+SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8`
+
+// ClusterConfig holds the configuration for a primary pool and its read replicas.
+type ClusterConfig struct {
+	Primary  Config
+	Replicas []Config
+
+	// MaxReplicationLag marks a replica unhealthy once its replay lag exceeds it.
+	// Zero disables the lag check.
+	MaxReplicationLag time.Duration
+	// HealthCheckInterval is how often replicas are pinged. Defaults to 5 seconds.
+	HealthCheckInterval time.Duration
+}
+
+type routeKey struct{}
+
+type route int
+
+const (
+	routePrimary route = iota + 1
+	routeReplica
+)
+
+// WithReadOnly marks ctx so that Cluster sends its queries to a healthy replica.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, routeReplica)
+}
+
+// WithPrimary marks ctx so that Cluster always uses the primary, e.g. to read your own writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, routePrimary)
+}
+
+func isReadOnly(ctx context.Context) bool {
+	r, _ := ctx.Value(routeKey{}).(route) //nolint:errcheck
+	return r == routeReplica
+}
+
+type replica struct {
+	name    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+	lag     atomic.Int64
+}
+
+// Cluster routes queries between a primary pool and N replica pools. Writes and
+// unhinted queries go to the primary; queries under WithReadOnly and read-only
+// transactions are load-balanced over healthy replicas, falling back to the
+// primary when every replica is down or lagging.
+type Cluster struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	next     atomic.Uint64
+
+	maxLag   time.Duration
+	interval time.Duration
+	log      *logrus.Logger
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewCluster connects to the primary with NewDatabase and opens the replica pools.
+// Replicas that are unreachable at startup are not fatal: they start unhealthy and
+// join the rotation once a health check succeeds.
+func NewCluster(ctx context.Context, log *logrus.Logger, cfg ClusterConfig) (*Cluster, error) {
+	primary, err := NewDatabase(ctx, log, cfg.Primary)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cluster{
+		primary:  primary,
+		maxLag:   cfg.MaxReplicationLag,
+		interval: cfg.HealthCheckInterval,
+		log:      log,
+		stop:     make(chan struct{}),
+	}
+	if c.interval <= 0 {
+		c.interval = defaultReplicaCheckInterval
+	}
+
+	for i, rc := range cfg.Replicas {
+		pgxCfg, err := newPoolConfig(rc)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+
+		pool, err := pgxpool.NewWithConfig(ctx, pgxCfg)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+
+		c.replicas = append(c.replicas, &replica{
+			name: fmt.Sprintf("replica-%d", i),
+			pool: pool,
+		})
+	}
+
+	c.checkReplicas(ctx)
+
+	if len(c.replicas) > 0 {
+		c.wg.Add(1)
+		go c.healthLoop()
+	}
+
+	return c, nil
+}
+
+// Primary returns the primary pool.
+func (c *Cluster) Primary() *pgxpool.Pool {
+	return c.primary
+}
+
+// Reader returns the next healthy replica pool in round-robin order, or the primary
+// when no replica is usable.
+func (c *Cluster) Reader() *pgxpool.Pool {
+	n := len(c.replicas)
+	if n == 0 {
+		return c.primary
+	}
+
+	start := c.next.Add(1)
+	for i := range n {
+		r := c.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r.pool
+		}
+	}
+
+	return c.primary
+}
+
+// Pool returns the pool a query under ctx would be routed to.
+func (c *Cluster) Pool(ctx context.Context) *pgxpool.Pool {
+	if isReadOnly(ctx) {
+		return c.Reader()
+	}
+	return c.primary
+}
+
+// Exec always runs on the primary.
+func (c *Cluster) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return c.primary.Exec(ctx, sql, args...)
+}
+
+// Query runs on a replica when ctx carries WithReadOnly, otherwise on the primary.
+func (c *Cluster) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return c.Pool(ctx).Query(ctx, sql, args...)
+}
+
+// QueryRow runs on a replica when ctx carries WithReadOnly, otherwise on the primary.
+func (c *Cluster) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return c.Pool(ctx).QueryRow(ctx, sql, args...)
+}
+
+// SendBatch always runs on the primary.
+func (c *Cluster) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return c.primary.SendBatch(ctx, b)
+}
+
+// CopyFrom always runs on the primary.
+func (c *Cluster) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return c.primary.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// Begin starts a transaction on the pool selected by ctx.
+func (c *Cluster) Begin(ctx context.Context) (pgx.Tx, error) {
+	return c.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx starts a transaction. Read-only transactions go to a replica, as do
+// transactions started under WithReadOnly.
+func (c *Cluster) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	if opts.AccessMode == pgx.ReadOnly {
+		return c.Reader().BeginTx(ctx, opts)
+	}
+	return c.Pool(ctx).BeginTx(ctx, opts)
+}
+
+// Close stops health checking and closes every pool.
+func (c *Cluster) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.wg.Wait()
+
+		for _, r := range c.replicas {
+			r.pool.Close()
+		}
+		c.primary.Close()
+	})
+}
+
+func (c *Cluster) healthLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.checkReplicas(context.Background())
+		}
+	}
+}
+
+// checkReplicas probes every replica concurrently, so a hung replica does not
+// delay the health of the others.
+func (c *Cluster) checkReplicas(ctx context.Context) {
+	ctx = WithoutTenant(ctx)
+
+	var wg sync.WaitGroup
+	for _, r := range c.replicas {
+		wg.Go(func() {
+			checkCtx, cancel := context.WithTimeout(ctx, c.interval)
+			defer cancel()
+			c.updateHealth(r, c.checkReplica(checkCtx, r))
+		})
+	}
+	wg.Wait()
+}
+
+func (c *Cluster) updateHealth(r *replica, err error) {
+	healthy := err == nil
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	entry := c.log.WithFields(logrus.Fields{
+		"replica": r.name,
+		"lag":     time.Duration(r.lag.Load()),
+	})
+	if healthy {
+		entry.Info("database replica is healthy")
+	} else {
+		entry.WithError(err).Warn("database replica is unhealthy, routing reads to primary")
+	}
+}
+
+func (c *Cluster) checkReplica(ctx context.Context, r *replica) error {
+	if c.maxLag <= 0 {
+		return r.pool.Ping(ctx)
+	}
+
+	var seconds float64
+	if err := r.pool.QueryRow(ctx, replicationLagQuery).Scan(&seconds); err != nil {
+		return err
+	}
+
+	lag := time.Duration(seconds * float64(time.Second))
+	r.lag.Store(int64(lag))
+	if lag > c.maxLag {
+		return fmt.Errorf("replication lag %s exceeds %s", lag, c.maxLag)
+	}
+
+	return nil
+}
@@ -16,7 +16,20 @@
 // Package postgres provides PostgreSQL database connection pooling utilities.
 package postgres
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/jackc/pgx/v5"
+)
+
+// PasswordProvider returns the password for a new connection, e.g. a short-lived
+// token from a secret manager. It is called before every connection attempt.
+type PasswordProvider func(ctx context.Context) (string, error)
+
+// ConnHook runs on every new connection before it joins the pool.
+type ConnHook func(ctx context.Context, conn *pgx.Conn) error
 
 // Config holds the configuration for PostgreSQL connection pooling.
 type Config struct {
@@ -26,4 +39,35 @@ type Config struct {
 	MaxConnIdleTime   time.Duration
 	HealthCheckPeriod time.Duration
 	ConnectTimeout    time.Duration
+
+	// MaxConnLifetime closes connections after this age; the jitter spreads the
+	// closes so the pool does not reconnect all at once.
+	MaxConnLifetime       time.Duration
+	MaxConnLifetimeJitter time.Duration
+
+	TLS TLSConfig
+	// PasswordProvider, when set, supplies the password for every new connection
+	// and overrides the one in DSN.
+	PasswordProvider PasswordProvider
+	// ApplicationName is reported in pg_stat_activity.
+	ApplicationName string
+	// AfterConnect hooks run in order on every new connection, e.g. to prepare
+	// statements. An error discards the connection.
+	AfterConnect []ConnHook
+
+	// StartupTimeout is the budget NewDatabase spends retrying the initial connection.
+	// Zero means a single attempt.
+	StartupTimeout time.Duration
+	StartupBackoff backoff.Policy
+
+	// TenantSetting enables row-level security support: the tenant from WithTenant is
+	// written to this session setting whenever a connection is acquired and cleared on
+	// release. TenantScoped implies DefaultTenantSetting when left empty.
+	TenantSetting string
+	// TenantScoped makes the pool fail closed: acquiring a connection without a tenant
+	// in the context returns ErrNoTenant, unless the context carries WithoutTenant.
+	TenantScoped bool
+	// TenantRole is switched to with SET ROLE while a tenant is active, typically a
+	// role without BYPASSRLS.
+	TenantRole string
 }
@@ -0,0 +1,257 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 14.20
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Sentinel errors returned by Classify. Use errors.Is to test for them and
+// errors.As with *ConstraintError to get the constraint details.
+var (
+	ErrNotFound             = errors.New("postgres: not found")
+	ErrUniqueViolation      = errors.New("postgres: unique violation")
+	ErrForeignKeyViolation  = errors.New("postgres: foreign key violation")
+	ErrCheckViolation       = errors.New("postgres: check violation")
+	ErrNotNullViolation     = errors.New("postgres: not null violation")
+	ErrSerializationFailure = errors.New("postgres: serialization failure")
+	ErrDeadlock             = errors.New("postgres: deadlock detected")
+	ErrLockNotAvailable     = errors.New("postgres: lock not available")
+	ErrQueryCanceled        = errors.New("postgres: query canceled")
+)
+
+// SQLSTATE codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	codeUniqueViolation      = "23505"
+	codeForeignKeyViolation  = "23503"
+	codeCheckViolation       = "23514"
+	codeNotNullViolation     = "23502"
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+	codeLockNotAvailable     = "55P03"
+	codeQueryCanceled        = "57014"
+	classConnectionException = "08"
+	classInvalidAuthSpec     = "28"
+)
+
+var sqlStateErrors = map[string]error{
+	codeUniqueViolation:      ErrUniqueViolation,
+	codeForeignKeyViolation:  ErrForeignKeyViolation,
+	codeCheckViolation:       ErrCheckViolation,
+	codeNotNullViolation:     ErrNotNullViolation,
+	codeSerializationFailure: ErrSerializationFailure,
+	codeDeadlockDetected:     ErrDeadlock,
+	codeLockNotAvailable:     ErrLockNotAvailable,
+	codeQueryCanceled:        ErrQueryCanceled,
+}
+
+// ConstraintError is a classified *pgconn.PgError. It matches its Kind with
+// errors.Is and unwraps to the original PgError.
+type ConstraintError struct {
+	Kind       error
+	Constraint string
+	Table      string
+	Column     string
+	Detail     string
+	Err        *pgconn.PgError
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("%s on constraint %q", e.Kind, e.Constraint)
+	}
+	return e.Kind.Error()
+}
+
+// Is reports whether target is the sentinel this error was classified as.
+func (e *ConstraintError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// Classify maps pgx.ErrNoRows to ErrNotFound and known SQLSTATE codes to a
+// *ConstraintError. Other errors are returned unchanged, nil stays nil.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	kind, ok := sqlStateErrors[pgErr.Code]
+	if !ok {
+		return err
+	}
+
+	return &ConstraintError{
+		Kind:       kind,
+		Constraint: pgErr.ConstraintName,
+		Table:      pgErr.TableName,
+		Column:     pgErr.ColumnName,
+		Detail:     pgErr.Detail,
+		Err:        pgErr,
+	}
+}
+
+// IsRetryable reports whether the operation that produced err can be retried,
+// typically by re-running the whole transaction.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	switch pgErr.Code {
+	case codeSerializationFailure, codeDeadlockDetected, codeLockNotAvailable:
+		return true
+	}
+
+	return len(pgErr.Code) == 5 && pgErr.Code[:2] == classConnectionException
+}
+
+// HTTPStatus returns the HTTP status code that best describes err.
+func HTTPStatus(err error) int {
+	err = Classify(err)
+
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidCursor),
+		errors.Is(err, ErrInvalidSort),
+		errors.Is(err, ErrInvalidFilter):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUniqueViolation):
+		return http.StatusConflict
+	case errors.Is(err, ErrForeignKeyViolation),
+		errors.Is(err, ErrCheckViolation),
+		errors.Is(err, ErrNotNullViolation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrQueryCanceled):
+		return http.StatusGatewayTimeout
+	case IsRetryable(err):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ConnectErrorKind tells why connecting to the database failed.
+type ConnectErrorKind string
+
+// Connection failure kinds reported by *ConnectError.
+const (
+	ConnectErrorUnknown ConnectErrorKind = "unknown"
+	ConnectErrorDNS     ConnectErrorKind = "dns"
+	ConnectErrorRefused ConnectErrorKind = "refused"
+	ConnectErrorTimeout ConnectErrorKind = "timeout"
+	ConnectErrorAuth    ConnectErrorKind = "auth"
+	ConnectErrorTLS     ConnectErrorKind = "tls"
+)
+
+// ConnectError is returned by NewDatabase when the database cannot be reached.
+type ConnectError struct {
+	Kind     ConnectErrorKind
+	Attempts int
+	Err      error
+}
+
+func newConnectError(err error, attempts int) *ConnectError {
+	return &ConnectError{Kind: classifyConnectError(err), Attempts: attempts, Err: err}
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("postgres: connect failed (%s) after %d attempt(s): %v", e.Kind, e.Attempts, e.Err)
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether retrying can help. Authentication and TLS failures
+// are configuration problems and are not retried.
+func (e *ConnectError) Retryable() bool {
+	return e.Kind != ConnectErrorAuth && e.Kind != ConnectErrorTLS
+}
+
+func classifyConnectError(err error) ConnectErrorKind {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if strings.HasPrefix(pgErr.Code, classInvalidAuthSpec) {
+			return ConnectErrorAuth
+		}
+		return ConnectErrorUnknown
+	}
+
+	var (
+		dnsErr       *net.DNSError
+		verifyErr    *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return ConnectErrorDNS
+	case errors.As(err, &verifyErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return ConnectErrorTLS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ConnectErrorRefused
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return ConnectErrorTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ConnectErrorTimeout
+	}
+
+	return ConnectErrorUnknown
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 14.47
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestClassify_Nil(t *testing.T) {
+	if err := Classify(nil); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
+
+func TestClassify_NoRows(t *testing.T) {
+	err := Classify(fmt.Errorf("find device: %w", pgx.ErrNoRows))
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound")
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Error("Expected pgx.ErrNoRows to remain in the chain")
+	}
+}
+
+func TestClassify_PgErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected error
+	}{
+		{"23505", ErrUniqueViolation},
+		{"23503", ErrForeignKeyViolation},
+		{"23514", ErrCheckViolation},
+		{"23502", ErrNotNullViolation},
+		{"40001", ErrSerializationFailure},
+		{"40P01", ErrDeadlock},
+		{"55P03", ErrLockNotAvailable},
+		{"57014", ErrQueryCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			pgErr := &pgconn.PgError{Code: tt.code, ConstraintName: "devices_jid_key", TableName: "devices"}
+			err := Classify(fmt.Errorf("insert device: %w", pgErr))
+
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+
+			var ce *ConstraintError
+			if !errors.As(err, &ce) {
+				t.Fatal("Expected *ConstraintError")
+			}
+			if ce.Constraint != "devices_jid_key" || ce.Table != "devices" {
+				t.Errorf("Unexpected constraint details: %+v", ce)
+			}
+
+			var unwrapped *pgconn.PgError
+			if !errors.As(err, &unwrapped) || unwrapped != pgErr {
+				t.Error("Expected original *pgconn.PgError in the chain")
+			}
+		})
+	}
+}
+
+func TestClassify_Unknown(t *testing.T) {
+	base := &pgconn.PgError{Code: "42P01"}
+	if err := Classify(base); err != error(base) {
+		t.Errorf("Expected unknown error unchanged, got %v", err)
+	}
+
+	plain := errors.New("boom")
+	if err := Classify(plain); err != plain {
+		t.Errorf("Expected plain error unchanged, got %v", err)
+	}
+}
+
+func TestConstraintError_Message(t *testing.T) {
+	err := Classify(&pgconn.PgError{Code: "23505", ConstraintName: "api_keys_hash_key"})
+	expected := `postgres: unique violation on constraint "api_keys_hash_key"`
+
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"serialization", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"unique", &pgconn.PgError{Code: "23505"}, false},
+		{"no rows", pgx.ErrNoRows, false},
+		{"plain", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"nil", nil, http.StatusOK},
+		{"not found", pgx.ErrNoRows, http.StatusNotFound},
+		{"unique", &pgconn.PgError{Code: "23505"}, http.StatusConflict},
+		{"foreign key", &pgconn.PgError{Code: "23503"}, http.StatusUnprocessableEntity},
+		{"check", &pgconn.PgError{Code: "23514"}, http.StatusUnprocessableEntity},
+		{"canceled", &pgconn.PgError{Code: "57014"}, http.StatusGatewayTimeout},
+		{"serialization", &pgconn.PgError{Code: "40001"}, http.StatusServiceUnavailable},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 11.32
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationHandler handles notifications received on a LISTEN channel.
+type NotificationHandler interface {
+	HandleNotification(ctx context.Context, n *pgconn.Notification) error
+}
+
+// GapHandler is implemented by handlers that need to resync after the listener
+// reconnects, because notifications sent while it was disconnected are lost.
+type GapHandler interface {
+	HandleGap(ctx context.Context, channel string) error
+}
+
+// NotificationHandlerFunc adapts a function to NotificationHandler.
+type NotificationHandlerFunc func(ctx context.Context, n *pgconn.Notification) error
+
+// HandleNotification calls f(ctx, n).
+func (f NotificationHandlerFunc) HandleNotification(ctx context.Context, n *pgconn.Notification) error {
+	return f(ctx, n)
+}
+
+// JSONHandler decodes each notification payload into T before calling fn.
+// When onGap is not nil it is called after reconnects.
+func JSONHandler[T any](fn func(ctx context.Context, payload T) error, onGap func(ctx context.Context) error) NotificationHandler {
+	return &jsonHandler[T]{fn: fn, onGap: onGap}
+}
+
+type jsonHandler[T any] struct {
+	fn    func(ctx context.Context, payload T) error
+	onGap func(ctx context.Context) error
+}
+
+func (h *jsonHandler[T]) HandleNotification(ctx context.Context, n *pgconn.Notification) error {
+	var payload T
+	if err := json.Unmarshal([]byte(n.Payload), &payload); err != nil {
+		return fmt.Errorf("decode notification payload: %w", err)
+	}
+	return h.fn(ctx, payload)
+}
+
+func (h *jsonHandler[T]) HandleGap(ctx context.Context, _ string) error {
+	if h.onGap == nil {
+		return nil
+	}
+	return h.onGap(ctx)
+}
+
+// Listener holds a dedicated connection that LISTENs on the registered channels and
+// dispatches notifications to their handlers. The connection is opened outside the
+// pool so it never takes a slot from regular queries.
+type Listener struct {
+	connConfig    *pgx.ConnConfig
+	beforeConnect func(context.Context, *pgx.ConnConfig) error
+	log           *logrus.Logger
+	backoff       backoff.Policy
+	handlers      map[string]NotificationHandler
+}
+
+// NewListener returns a Listener that connects with the same settings as pool,
+// including its password provider.
+func NewListener(pool *pgxpool.Pool, log *logrus.Logger, policy backoff.Policy) *Listener {
+	cfg := pool.Config()
+	return &Listener{
+		connConfig:    cfg.ConnConfig,
+		beforeConnect: cfg.BeforeConnect,
+		log:           log,
+		backoff:       policy,
+		handlers:      make(map[string]NotificationHandler),
+	}
+}
+
+// Handle registers h for channel. It must be called before Listen.
+func (l *Listener) Handle(channel string, h NotificationHandler) {
+	l.handlers[channel] = h
+}
+
+// Listen blocks until ctx is canceled, reconnecting with backoff whenever the
+// connection is lost. After every reconnect, handlers implementing GapHandler are
+// told that notifications may have been missed.
+func (l *Listener) Listen(ctx context.Context) error {
+	if len(l.handlers) == 0 {
+		return fmt.Errorf("listener: no channels registered")
+	}
+
+	connected := false
+	attempt := 0
+	for {
+		err := l.listen(ctx, connected, func() {
+			connected = true
+			attempt = 0
+		})
+		if ctx.Err() != nil {
+			l.log.Info("Postgres listener stopping")
+			return nil
+		}
+
+		attempt++
+		delay := l.backoff.Delay(attempt)
+		l.log.WithError(err).WithFields(logrus.Fields{
+			"attempt": attempt,
+			"retry":   delay,
+		}).Warn("postgres listener disconnected, reconnecting")
+
+		if backoff.Sleep(ctx, delay) != nil {
+			return nil
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context, reconnect bool, onReady func()) error {
+	connConfig := l.connConfig.Copy()
+	if l.beforeConnect != nil {
+		if err := l.beforeConnect(ctx, connConfig); err != nil {
+			return err
+		}
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close(context.Background()) //nolint:errcheck
+	}()
+
+	for channel := range l.handlers {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+	}
+
+	onReady()
+	l.log.WithField("channels", len(l.handlers)).Info("Postgres listener connected")
+
+	if reconnect {
+		for channel, h := range l.handlers {
+			gh, ok := h.(GapHandler)
+			if !ok {
+				continue
+			}
+			if err := gh.HandleGap(ctx, channel); err != nil {
+				l.log.WithError(err).WithField("channel", channel).Error("postgres listener gap handler failed")
+			}
+		}
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		h, ok := l.handlers[n.Channel]
+		if !ok {
+			continue
+		}
+
+		if err := h.HandleNotification(ctx, n); err != nil {
+			l.log.WithError(err).WithField("channel", n.Channel).Error("postgres notification handler failed")
+		}
+	}
+}
+
+// Notify sends payload on channel. Notifications are delivered when the surrounding
+// transaction, if any, commits.
+func Notify(ctx context.Context, q Querier, channel, payload string) error {
+	_, err := q.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 12.01
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+)
+
+type deviceChanged struct {
+	DeviceID string `json:"device_id"`
+}
+
+func TestJSONHandler_Decode(t *testing.T) {
+	var got deviceChanged
+	h := JSONHandler(func(_ context.Context, p deviceChanged) error {
+		got = p
+		return nil
+	}, nil)
+
+	n := &pgconn.Notification{Channel: "device_config", Payload: `{"device_id":"628123@s.whatsapp.net"}`}
+	if err := h.HandleNotification(context.Background(), n); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got.DeviceID != "628123@s.whatsapp.net" {
+		t.Errorf("Expected decoded device id, got %q", got.DeviceID)
+	}
+}
+
+func TestJSONHandler_InvalidPayload(t *testing.T) {
+	h := JSONHandler(func(_ context.Context, _ deviceChanged) error {
+		t.Error("Handler should not be called for invalid payload")
+		return nil
+	}, nil)
+
+	n := &pgconn.Notification{Channel: "device_config", Payload: "not json"}
+	if err := h.HandleNotification(context.Background(), n); err == nil {
+		t.Error("Expected decode error")
+	}
+}
+
+func TestJSONHandler_Gap(t *testing.T) {
+	resynced := false
+	h := JSONHandler(func(_ context.Context, _ deviceChanged) error { return nil }, func(_ context.Context) error {
+		resynced = true
+		return nil
+	})
+
+	gh, ok := h.(GapHandler)
+	if !ok {
+		t.Fatal("Expected JSON handler to implement GapHandler")
+	}
+
+	if err := gh.HandleGap(context.Background(), "device_config"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !resynced {
+		t.Error("Expected gap callback to be called")
+	}
+}
+
+func TestListener_NoChannels(t *testing.T) {
+	l := NewListener(newLazyPool(t, 1), logrus.New(), backoff.Policy{})
+
+	if err := l.Listen(context.Background()); err == nil {
+		t.Error("Expected error when no channels are registered")
+	}
+}
+
+func TestListener_Integration(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database test: TEST_DATABASE_URL not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := NewDatabase(ctx, logrus.New(), Config{DSN: dsn, MaxConns: 2, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create database pool: %v", err)
+	}
+	defer pool.Close()
+
+	received := make(chan string, 1)
+	l := NewListener(pool, logrus.New(), backoff.Policy{})
+	l.Handle("device_config", NotificationHandlerFunc(func(_ context.Context, n *pgconn.Notification) error {
+		received <- n.Payload
+		return nil
+	}))
+
+	go func() {
+		_ = l.Listen(ctx) //nolint:errcheck
+	}()
+
+	// Notifications sent before LISTEN is established are lost, so keep sending.
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case payload := <-received:
+			if payload != "changed" {
+				t.Errorf("Expected payload 'changed', got %q", payload)
+			}
+			return
+		case <-ticker.C:
+			if err := Notify(ctx, pool, "device_config", "changed"); err != nil {
+				t.Fatalf("Failed to notify: %v", err)
+			}
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for notification")
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 08.12
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector exposes pgxpool.Stat() as Prometheus metrics labelled by pool name.
+type PoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	totalConns           *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	emptyAcquireWaitTime *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+	maxLifetimeDestroys  *prometheus.Desc
+	maxIdleDestroys      *prometheus.Desc
+}
+
+// NewPoolCollector returns a collector for pool. Register one collector per pool,
+// each with a distinct name, so services with several pools can tell them apart.
+func NewPoolCollector(name string, pool *pgxpool.Pool) *PoolCollector {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("pgxpool_"+metric, help, nil, labels)
+	}
+
+	return &PoolCollector{
+		pool:                 pool,
+		acquiredConns:        desc("acquired_conns", "Number of currently acquired connections in the pool."),
+		idleConns:            desc("idle_conns", "Number of currently idle connections in the pool."),
+		totalConns:           desc("total_conns", "Total number of connections currently in the pool."),
+		constructingConns:    desc("constructing_conns", "Number of connections with construction in progress."),
+		maxConns:             desc("max_conns", "Maximum size of the pool."),
+		acquireCount:         desc("acquire_count_total", "Cumulative count of successful acquires from the pool."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total time spent on successful acquires from the pool."),
+		emptyAcquireCount:    desc("empty_acquire_count_total", "Cumulative count of acquires that waited for a connection because the pool was empty."),
+		emptyAcquireWaitTime: desc("empty_acquire_wait_seconds_total", "Total time spent waiting for a connection because the pool was empty."),
+		canceledAcquireCount: desc("canceled_acquire_count_total", "Cumulative count of acquires canceled by a context."),
+		newConnsCount:        desc("new_conns_total", "Cumulative count of new connections opened."),
+		maxLifetimeDestroys:  desc("max_lifetime_destroy_count_total", "Cumulative count of connections destroyed because they exceeded MaxConnLifetime."),
+		maxIdleDestroys:      desc("max_idle_destroy_count_total", "Cumulative count of connections destroyed because they exceeded MaxConnIdleTime."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.constructingConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquireCount
+	ch <- c.emptyAcquireWaitTime
+	ch <- c.canceledAcquireCount
+	ch <- c.newConnsCount
+	ch <- c.maxLifetimeDestroys
+	ch <- c.maxIdleDestroys
+}
+
+// Collect implements prometheus.Collector.
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+
+	gauge(c.acquiredConns, float64(s.AcquiredConns()))
+	gauge(c.idleConns, float64(s.IdleConns()))
+	gauge(c.totalConns, float64(s.TotalConns()))
+	gauge(c.constructingConns, float64(s.ConstructingConns()))
+	gauge(c.maxConns, float64(s.MaxConns()))
+	counter(c.acquireCount, float64(s.AcquireCount()))
+	counter(c.acquireDuration, s.AcquireDuration().Seconds())
+	counter(c.emptyAcquireCount, float64(s.EmptyAcquireCount()))
+	counter(c.emptyAcquireWaitTime, s.EmptyAcquireWaitTime().Seconds())
+	counter(c.canceledAcquireCount, float64(s.CanceledAcquireCount()))
+	counter(c.newConnsCount, float64(s.NewConnsCount()))
+	counter(c.maxLifetimeDestroys, float64(s.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleDestroys, float64(s.MaxIdleDestroyCount()))
+}
+
+// RegisterPoolMetrics registers a PoolCollector for pool on the default Prometheus registry,
+// so the metrics are served by metrics.PrometheusHandler.
+func RegisterPoolMetrics(name string, pool *pgxpool.Pool) error {
+	return prometheus.Register(NewPoolCollector(name, pool))
+}
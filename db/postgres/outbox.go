@@ -0,0 +1,309 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 10.20
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultOutboxTable is the table used when Outbox.Table is empty.
+const DefaultOutboxTable = "outbox"
+
+const outboxTableDDL = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+	id              BIGSERIAL PRIMARY KEY,
+	topic           TEXT        NOT NULL,
+	msg_key         TEXT        NOT NULL DEFAULT '',
+	client_jid      TEXT        NOT NULL DEFAULT '',
+	event_id        TEXT        NOT NULL,
+	event_name      TEXT        NOT NULL DEFAULT '',
+	payload         BYTEA       NOT NULL,
+	headers         JSONB       NOT NULL DEFAULT '{}',
+	attempts        INT         NOT NULL DEFAULT 0,
+	last_error      TEXT,
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+	sent_at         TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (next_attempt_at, id) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS %[3]s ON %[1]s (msg_key, id) WHERE sent_at IS NULL;`
+
+// Only the oldest unsent row of each key is eligible, so a failing row holds back
+// the rows queued behind it and delivery stays ordered per key.
+const outboxClaimSQL = `
+SELECT id, topic, msg_key, payload, headers, attempts
+FROM %[1]s o
+WHERE o.sent_at IS NULL
+  AND o.next_attempt_at <= now()
+  AND (o.msg_key = '' OR NOT EXISTS (
+	SELECT 1 FROM %[1]s p
+	WHERE p.msg_key = o.msg_key AND p.sent_at IS NULL AND p.id < o.id
+  ))
+ORDER BY o.id
+LIMIT $1
+FOR UPDATE SKIP LOCKED`
+
+// Outbox stores events in Postgres inside the caller's transaction so they are
+// published only if, and always after, the transaction commits.
+type Outbox struct {
+	Table string
+}
+
+// NewOutbox returns an Outbox backed by table, or DefaultOutboxTable when table is empty.
+func NewOutbox(table string) *Outbox {
+	return &Outbox{Table: table}
+}
+
+func (o *Outbox) table() string {
+	if o.Table == "" {
+		return DefaultOutboxTable
+	}
+	return o.Table
+}
+
+// CreateTable creates the outbox table and its indexes if they do not exist, and
+// adds columns missing from tables created by older versions.
+func (o *Outbox) CreateTable(ctx context.Context, pool *pgxpool.Pool) error {
+	t := o.table()
+	_, err := pool.Exec(WithoutTenant(ctx), fmt.Sprintf(outboxTableDDL,
+		pgx.Identifier{t}.Sanitize(),
+		pgx.Identifier{t + "_pending_idx"}.Sanitize(),
+		pgx.Identifier{t + "_key_idx"}.Sanitize(),
+	))
+	return err
+}
+
+// Add marshals evt as JSON and inserts it into the outbox within tx. The event
+// name, content type, trace ID of ctx and a non-empty clientJID are stored as
+// headers.
+func (o *Outbox) Add(ctx context.Context, tx pgx.Tx, topic string, evt event.Event, clientJID string) error {
+	return o.AddWith(ctx, tx, topic, evt, clientJID)
+}
+
+// AddWith is Add through any Querier, for callers such as audit that accept one.
+// q should still be the transaction that writes the change the event describes.
+func (o *Outbox) AddWith(ctx context.Context, q Querier, topic string, evt event.Event, clientJID string) error {
+	msg, err := producer.EventMessage(topic, evt)
+	if err != nil {
+		return fmt.Errorf("outbox: marshal event: %w", err)
+	}
+	if clientJID != "" {
+		msg.Headers[producer.HeaderDeviceID] = clientJID
+	}
+
+	_, err = q.Exec(ctx,
+		fmt.Sprintf(`INSERT INTO %s (topic, msg_key, client_jid, event_id, event_name, payload, headers)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`, pgx.Identifier{o.table()}.Sanitize()),
+		topic, evt.EventKey(), clientJID, evt.EventID(), evt.EventName(), msg.Value, producer.Headers(ctx, msg),
+	)
+	return err
+}
+
+// OutboxRelayConfig holds the configuration for an OutboxRelay.
+type OutboxRelayConfig struct {
+	BatchSize    int           // rows claimed per round, default 100
+	PollInterval time.Duration // wait between empty rounds, default 1s
+	FlushTimeout time.Duration // how long to wait for a batch to be delivered, default 5s
+	Backoff      backoff.Policy
+}
+
+// OutboxRelay polls the outbox and publishes pending rows through a MessageProducer.
+// Rows are marked sent only after their own delivery is acknowledged, so delivery
+// is at least once; consumers should deduplicate by event ID. Producers that
+// implement producer.SyncSender, such as the Kafka producer, are waited on per
+// message; any other producer must report delivery errors from Send.
+type OutboxRelay struct {
+	pool     *pgxpool.Pool
+	outbox   *Outbox
+	producer producer.MessageProducer
+	log      *logrus.Logger
+	cfg      OutboxRelayConfig
+}
+
+// NewOutboxRelay returns a relay that publishes rows of outbox through p.
+func NewOutboxRelay(pool *pgxpool.Pool, outbox *Outbox, p producer.MessageProducer, log *logrus.Logger, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = 5 * time.Second
+	}
+
+	return &OutboxRelay{
+		pool:     pool,
+		outbox:   outbox,
+		producer: p,
+		log:      log,
+		cfg:      cfg,
+	}
+}
+
+// Run relays outbox rows until ctx is canceled.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	r.log.Info("Outbox relay started")
+
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.log.WithError(err).Error("outbox relay round failed")
+		}
+
+		if n > 0 && err == nil {
+			continue
+		}
+
+		if backoff.Sleep(ctx, r.cfg.PollInterval) != nil {
+			r.log.Info("Outbox relay stopping")
+			return nil
+		}
+	}
+}
+
+type outboxRow struct {
+	id       int64
+	topic    string
+	key      string
+	payload  []byte
+	headers  map[string]string
+	attempts int
+}
+
+func (o outboxRow) message() producer.Message {
+	return producer.Message{
+		Topic:   o.topic,
+		Key:     []byte(o.key),
+		Value:   o.payload,
+		Headers: o.headers,
+	}
+}
+
+// RelayOnce claims one batch of pending rows, publishes them and records the
+// outcome. It returns the number of rows claimed.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	ctx = WithoutTenant(ctx)
+	table := pgx.Identifier{r.outbox.table()}.Sanitize()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck
+	}()
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(outboxClaimSQL, table), r.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	batch, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (outboxRow, error) {
+		var o outboxRow
+		err := row.Scan(&o.id, &o.topic, &o.key, &o.payload, &o.headers, &o.attempts)
+		return o, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	failed := r.publish(ctx, batch)
+
+	for _, o := range batch {
+		var tag pgconn.CommandTag
+		if sendErr, ok := failed[o.id]; ok {
+			r.log.WithFields(logrus.Fields{
+				"outbox_id": o.id,
+				"topic":     o.topic,
+				"attempts":  o.attempts + 1,
+			}).WithError(sendErr).Warn("outbox publish failed, scheduling retry")
+
+			tag, err = tx.Exec(ctx,
+				fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, last_error = $2,
+					next_attempt_at = now() + $3::interval WHERE id = $1`, table),
+				o.id, sendErr.Error(), r.cfg.Backoff.Delay(o.attempts+1),
+			)
+		} else {
+			tag, err = tx.Exec(ctx,
+				fmt.Sprintf(`UPDATE %s SET sent_at = now(), last_error = NULL WHERE id = $1`, table),
+				o.id,
+			)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if tag.RowsAffected() != 1 {
+			return 0, errors.New("outbox: claimed row disappeared")
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return len(batch), nil
+}
+
+// publish sends batch and returns the delivery error of every row that failed.
+// A batch holds at most one row per key, so rows are sent concurrently.
+func (r *OutboxRelay) publish(ctx context.Context, batch []outboxRow) map[int64]error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.FlushTimeout)
+	defer cancel()
+
+	sp, ok := r.producer.(producer.SyncSender)
+	if !ok {
+		failed := make(map[int64]error)
+		for _, o := range batch {
+			if err := r.producer.Send(ctx, o.message()); err != nil {
+				failed[o.id] = err
+			}
+		}
+		return failed
+	}
+
+	errs := make([]error, len(batch))
+	var wg sync.WaitGroup
+	for i, o := range batch {
+		wg.Go(func() {
+			_, _, errs[i] = sp.SendSync(ctx, o.message())
+		})
+	}
+	wg.Wait()
+
+	failed := make(map[int64]error)
+	for i, o := range batch {
+		if errs[i] != nil {
+			failed[o.id] = errs[i]
+		}
+	}
+	return failed
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 11.04
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/observability/logging/ctxmeta"
+	"github.com/sirupsen/logrus"
+)
+
+type testEvent struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Body string `json:"body"`
+}
+
+func (e testEvent) EventID() string   { return e.ID }
+func (e testEvent) EventName() string { return "test.event" }
+func (e testEvent) EventKey() string  { return e.Key }
+
+type recordingProducer struct {
+	mu      sync.Mutex
+	sent    []string
+	headers []map[string]string
+	fail    map[string]bool
+}
+
+func (p *recordingProducer) Send(_ context.Context, msg producer.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.fail[string(msg.Key)] {
+		return errors.New("broker unavailable")
+	}
+	p.sent = append(p.sent, string(msg.Key))
+	p.headers = append(p.headers, msg.Headers)
+	return nil
+}
+
+func (p *recordingProducer) Flush(_ int) int { return 0 }
+func (p *recordingProducer) Close() error    { return nil }
+
+// asyncProducer queues every message in Send and flushes instantly, but fails
+// the delivery of keys in fail, as Kafka does after a message timeout.
+type asyncProducer struct {
+	recordingProducer
+}
+
+func (p *asyncProducer) Send(_ context.Context, msg producer.Message) error {
+	return nil
+}
+
+func (p *asyncProducer) SendSync(ctx context.Context, msg producer.Message) (int32, int64, error) {
+	return 0, 0, p.recordingProducer.Send(ctx, msg)
+}
+
+func TestOutboxRelay_PublishWaitsForDelivery(t *testing.T) {
+	p := &asyncProducer{recordingProducer{fail: map[string]bool{"b": true}}}
+	relay := NewOutboxRelay(nil, NewOutbox(""), p, logrus.New(), OutboxRelayConfig{})
+
+	failed := relay.publish(context.Background(), []outboxRow{
+		{id: 1, topic: "events", key: "a"},
+		{id: 2, topic: "events", key: "b"},
+	})
+	if p.Flush(0) != 0 {
+		t.Fatal("Expected the producer queue to be empty")
+	}
+	if len(failed) != 1 || failed[2] == nil {
+		t.Errorf("Expected only row 2 to fail despite an empty flush, got %v", failed)
+	}
+	if len(p.sent) != 1 || p.sent[0] != "a" {
+		t.Errorf("Expected row 1 to be delivered, got %v", p.sent)
+	}
+}
+
+func TestOutbox_DefaultTable(t *testing.T) {
+	if got := NewOutbox("").table(); got != DefaultOutboxTable {
+		t.Errorf("Expected %s, got %s", DefaultOutboxTable, got)
+	}
+
+	if got := NewOutbox("events_outbox").table(); got != "events_outbox" {
+		t.Errorf("Expected events_outbox, got %s", got)
+	}
+}
+
+func TestNewOutboxRelay_Defaults(t *testing.T) {
+	r := NewOutboxRelay(nil, NewOutbox(""), &recordingProducer{}, logrus.New(), OutboxRelayConfig{})
+
+	if r.cfg.BatchSize != 100 {
+		t.Errorf("Expected BatchSize 100, got %d", r.cfg.BatchSize)
+	}
+	if r.cfg.PollInterval != time.Second {
+		t.Errorf("Expected PollInterval 1s, got %v", r.cfg.PollInterval)
+	}
+	if r.cfg.FlushTimeout != 5*time.Second {
+		t.Errorf("Expected FlushTimeout 5s, got %v", r.cfg.FlushTimeout)
+	}
+}
+
+func TestOutboxRelay_Integration(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database test: TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := NewDatabase(ctx, logrus.New(), Config{DSN: dsn, MaxConns: 4, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create database pool: %v", err)
+	}
+	defer pool.Close()
+
+	outbox := NewOutbox("outbox_test")
+	if err := outbox.CreateTable(ctx, pool); err != nil {
+		t.Fatalf("Failed to create outbox table: %v", err)
+	}
+	defer func() {
+		_, _ = pool.Exec(ctx, "DROP TABLE outbox_test") //nolint:errcheck
+	}()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin tx: %v", err)
+	}
+	traced := ctxmeta.WithTraceID(ctx, "trace-1")
+	for _, evt := range []testEvent{{ID: "1", Key: "a"}, {ID: "2", Key: "b"}, {ID: "3", Key: "a"}} {
+		if err := outbox.Add(traced, tx, "events", evt, "jid-"+evt.ID); err != nil {
+			t.Fatalf("Failed to add event: %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	p := &recordingProducer{fail: map[string]bool{"b": true}}
+	relay := NewOutboxRelay(pool, outbox, p, logrus.New(), OutboxRelayConfig{})
+
+	// Only the head row of each key is eligible per round.
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 2 {
+		t.Fatalf("Expected 2 rows claimed, got %d (err: %v)", n, err)
+	}
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected 1 row claimed, got %d (err: %v)", n, err)
+	}
+
+	if len(p.sent) != 2 || p.sent[0] != "a" || p.sent[1] != "a" {
+		t.Errorf("Expected key a to be sent twice in order, got %v", p.sent)
+	}
+	if h := p.headers[0]; h[producer.HeaderEventName] != "test.event" || h[producer.HeaderTraceID] != "trace-1" ||
+		h[producer.HeaderDeviceID] != "jid-1" || h[producer.HeaderContentType] != producer.ContentTypeJSON {
+		t.Errorf("Expected stored headers to be relayed, got %v", h)
+	}
+
+	var attempts int
+	if err := pool.QueryRow(ctx, "SELECT attempts FROM outbox_test WHERE msg_key = 'b'").Scan(&attempts); err != nil {
+		t.Fatalf("Failed to read failed row: %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected failed row to record 1 attempt, got %d", attempts)
+	}
+}
@@ -0,0 +1,352 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 17.55
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/pagination"
+)
+
+// Pagination errors. They describe bad client input and map to HTTP 400.
+var (
+	ErrInvalidCursor = pagination.ErrInvalidCursor
+	ErrInvalidSort   = errors.New("postgres: invalid sort")
+	ErrInvalidFilter = errors.New("postgres: invalid filter")
+)
+
+// The request types live in package pagination so the HTTP layer can parse them
+// without depending on Postgres.
+type (
+	FilterOp    = pagination.FilterOp
+	SortField   = pagination.SortField
+	Filter      = pagination.Filter
+	PageRequest = pagination.PageRequest
+)
+
+// Supported filter operators.
+const (
+	OpEq    = pagination.OpEq
+	OpNe    = pagination.OpNe
+	OpLt    = pagination.OpLt
+	OpLte   = pagination.OpLte
+	OpGt    = pagination.OpGt
+	OpGte   = pagination.OpGte
+	OpIn    = pagination.OpIn
+	OpLike  = pagination.OpLike
+	OpExist = pagination.OpExist
+)
+
+var filterOpSQL = map[FilterOp]string{
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpLt:  "<",
+	OpLte: "<=",
+	OpGt:  ">",
+	OpGte: ">=",
+}
+
+// FilterSpec allow-lists a filter key.
+type FilterSpec struct {
+	Column string
+	Ops    []FilterOp // defaults to OpEq only
+	// Parse converts the raw value before binding. Nil binds the string as is.
+	Parse func(string) (any, error)
+}
+
+// KeysetQuery describes a list query that is paginated by keyset instead of OFFSET.
+// Every column name comes from the query definition, never from the request, and
+// every value is bound as a parameter.
+type KeysetQuery struct {
+	// Select is the query up to and excluding WHERE, e.g. "SELECT id, jid FROM devices".
+	Select string
+	// Where holds fixed conditions ANDed with the request's filters. They may use
+	// placeholders $1..$n bound to Args.
+	Where []string
+	Args  []any
+
+	Sortable    map[string]string // sort key -> column
+	Filterable  map[string]FilterSpec
+	TieBreaker  string // unique column appended to every sort, e.g. "id"
+	DefaultSort []SortField
+
+	DefaultLimit int // default 20
+	MaxLimit     int // default 100
+}
+
+// KeysetPlan is a built query. Run SQL with Args, pass the rows to TrimPage and
+// build the next cursor from the last row with NextCursor.
+type KeysetPlan struct {
+	SQL   string
+	Args  []any
+	Limit int
+
+	columns []string
+	desc    []bool
+}
+
+// FilterKeys returns the filter keys of q, for ParsePageRequest in the HTTP
+// server package.
+func (q KeysetQuery) FilterKeys() []string {
+	return slices.Sorted(maps.Keys(q.Filterable))
+}
+
+// Build validates req against the allow-lists and returns the SQL for one page.
+func (q KeysetQuery) Build(req PageRequest) (*KeysetPlan, error) {
+	plan := &KeysetPlan{Args: slices.Clone(q.Args)}
+
+	plan.Limit = req.Limit
+	if plan.Limit <= 0 {
+		plan.Limit = q.DefaultLimit
+		if plan.Limit <= 0 {
+			plan.Limit = 20
+		}
+	}
+	maxLimit := q.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = 100
+	}
+	plan.Limit = min(plan.Limit, maxLimit)
+
+	sort := req.Sort
+	if len(sort) == 0 {
+		sort = q.DefaultSort
+	}
+	for _, s := range sort {
+		col, ok := q.Sortable[s.Key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not sortable", ErrInvalidSort, s.Key)
+		}
+		if slices.Contains(plan.columns, col) {
+			continue
+		}
+		plan.columns = append(plan.columns, col)
+		plan.desc = append(plan.desc, s.Desc)
+	}
+	if q.TieBreaker != "" && !slices.Contains(plan.columns, q.TieBreaker) {
+		desc := len(plan.desc) > 0 && plan.desc[len(plan.desc)-1]
+		plan.columns = append(plan.columns, q.TieBreaker)
+		plan.desc = append(plan.desc, desc)
+	}
+	if len(plan.columns) == 0 {
+		return nil, fmt.Errorf("%w: no sort order", ErrInvalidSort)
+	}
+
+	where := slices.Clone(q.Where)
+	for _, f := range req.Filters {
+		cond, err := q.filterSQL(plan, f)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, cond)
+	}
+
+	if req.Cursor != "" {
+		cond, err := plan.keysetSQL(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, cond)
+	}
+
+	var b strings.Builder
+	b.WriteString(q.Select)
+	if len(where) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(where, " AND "))
+	}
+
+	order := make([]string, len(plan.columns))
+	for i, col := range plan.columns {
+		order[i] = col + " ASC"
+		if plan.desc[i] {
+			order[i] = col + " DESC"
+		}
+	}
+	b.WriteString(" ORDER BY ")
+	b.WriteString(strings.Join(order, ", "))
+	fmt.Fprintf(&b, " LIMIT %d", plan.Limit+1)
+
+	plan.SQL = b.String()
+	return plan, nil
+}
+
+func (p *KeysetPlan) bind(v any) string {
+	p.Args = append(p.Args, v)
+	return fmt.Sprintf("$%d", len(p.Args))
+}
+
+func (q KeysetQuery) filterSQL(plan *KeysetPlan, f Filter) (string, error) {
+	spec, ok := q.Filterable[f.Key]
+	if !ok {
+		return "", fmt.Errorf("%w: %q is not filterable", ErrInvalidFilter, f.Key)
+	}
+
+	op := f.Op
+	if op == "" {
+		op = OpEq
+	}
+	ops := spec.Ops
+	if len(ops) == 0 {
+		ops = []FilterOp{OpEq}
+	}
+	if !slices.Contains(ops, op) {
+		return "", fmt.Errorf("%w: operator %q not allowed on %q", ErrInvalidFilter, op, f.Key)
+	}
+
+	parse := func(raw string) (any, error) {
+		if spec.Parse == nil {
+			return raw, nil
+		}
+		v, err := spec.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidFilter, f.Key, err)
+		}
+		return v, nil
+	}
+
+	switch op {
+	case OpExist:
+		if f.Value == "false" {
+			return spec.Column + " IS NULL", nil
+		}
+		return spec.Column + " IS NOT NULL", nil
+	case OpLike:
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(f.Value)
+		return spec.Column + " LIKE " + plan.bind(escaped+"%"), nil
+	case OpIn:
+		parts := strings.Split(f.Value, ",")
+		values := make([]any, 0, len(parts))
+		for _, raw := range parts {
+			v, err := parse(strings.TrimSpace(raw))
+			if err != nil {
+				return "", err
+			}
+			values = append(values, v)
+		}
+		return spec.Column + " = ANY(" + plan.bind(values) + ")", nil
+	default:
+		sqlOp, ok := filterOpSQL[op]
+		if !ok {
+			return "", fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, op)
+		}
+		v, err := parse(f.Value)
+		if err != nil {
+			return "", err
+		}
+		return spec.Column + " " + sqlOp + " " + plan.bind(v), nil
+	}
+}
+
+func (p *KeysetPlan) signature() string {
+	parts := make([]string, len(p.columns))
+	for i, col := range p.columns {
+		parts[i] = col
+		if p.desc[i] {
+			parts[i] = "-" + col
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// keysetSQL expands the cursor into (a > $1) OR (a = $1 AND b < $2) OR ...,
+// which supports mixed sort directions.
+func (p *KeysetPlan) keysetSQL(cursor string) (string, error) {
+	tok, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		return "", err
+	}
+	if tok.Sort != p.signature() || len(tok.Values) != len(p.columns) {
+		return "", fmt.Errorf("%w: sort order changed", ErrInvalidCursor)
+	}
+
+	params := make([]string, len(tok.Values))
+	for i, v := range tok.Values {
+		params[i] = p.bind(v)
+	}
+
+	ors := make([]string, len(p.columns))
+	for i, col := range p.columns {
+		ands := make([]string, 0, i+1)
+		for j := range i {
+			ands = append(ands, p.columns[j]+" = "+params[j])
+		}
+		op := " > "
+		if p.desc[i] {
+			op = " < "
+		}
+		ands = append(ands, col+op+params[i])
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", nil
+}
+
+// NextCursor encodes the sort values of the last row on the page, in sort order
+// with the tie-breaker last. Without values, or with only nil values, there is no
+// next page and the cursor is empty.
+func (p *KeysetPlan) NextCursor(values ...any) (string, error) {
+	if !slices.ContainsFunc(values, func(v any) bool { return !isNil(v) }) {
+		return "", nil
+	}
+	if len(values) != len(p.columns) {
+		return "", fmt.Errorf("postgres: cursor needs %d values, got %d", len(p.columns), len(values))
+	}
+
+	tok := pagination.Cursor{Sort: p.signature(), Values: make([]string, len(values))}
+	for i, v := range values {
+		if isNil(v) {
+			return "", fmt.Errorf("postgres: cursor value for %s is nil", p.columns[i])
+		}
+		switch v := v.(type) {
+		case time.Time:
+			tok.Values[i] = v.Format(time.RFC3339Nano)
+		case fmt.Stringer:
+			tok.Values[i] = v.String()
+		default:
+			tok.Values[i] = fmt.Sprint(v)
+		}
+	}
+
+	return pagination.EncodeCursor(tok)
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// TrimPage drops the look-ahead row fetched by the plan and reports whether
+// another page exists.
+func TrimPage[T any](items []T, limit int) ([]T, bool) {
+	if len(items) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 19.05
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func messagesQuery() KeysetQuery {
+	return KeysetQuery{
+		Select: "SELECT id, status, created_at FROM messages",
+		Where:  []string{"device_id = $1"},
+		Args:   []any{"device-1"},
+		Sortable: map[string]string{
+			"created_at": "created_at",
+			"status":     "status",
+		},
+		Filterable: map[string]FilterSpec{
+			"status": {Column: "status", Ops: []FilterOp{OpEq, OpIn}},
+			"id": {Column: "id", Ops: []FilterOp{OpGt}, Parse: func(s string) (any, error) {
+				return strconv.ParseInt(s, 10, 64)
+			}},
+			"to": {Column: "recipient", Ops: []FilterOp{OpLike}},
+		},
+		TieBreaker:  "id",
+		DefaultSort: []SortField{{Key: "created_at", Desc: true}},
+		MaxLimit:    50,
+	}
+}
+
+func TestKeysetQuery_FirstPage(t *testing.T) {
+	plan, err := messagesQuery().Build(PageRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "SELECT id, status, created_at FROM messages WHERE device_id = $1 ORDER BY created_at DESC, id DESC LIMIT 21"
+	if plan.SQL != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, plan.SQL)
+	}
+	if plan.Limit != 20 || len(plan.Args) != 1 {
+		t.Errorf("Unexpected plan: limit=%d args=%v", plan.Limit, plan.Args)
+	}
+}
+
+func TestKeysetQuery_Filters(t *testing.T) {
+	plan, err := messagesQuery().Build(PageRequest{
+		Limit: 500,
+		Sort:  []SortField{{Key: "status"}},
+		Filters: []Filter{
+			{Key: "status", Op: OpIn, Value: "sent, delivered"},
+			{Key: "id", Op: OpGt, Value: "42"},
+			{Key: "to", Op: OpLike, Value: "62_%"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := `SELECT id, status, created_at FROM messages WHERE device_id = $1 AND status = ANY($2) AND id > $3 AND recipient LIKE $4 ORDER BY status ASC, id ASC LIMIT 51`
+	if plan.SQL != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, plan.SQL)
+	}
+	if plan.Args[2] != int64(42) {
+		t.Errorf("Expected parsed int64 filter value, got %#v", plan.Args[2])
+	}
+	if plan.Args[3] != `62\_\%%` {
+		t.Errorf("Expected escaped LIKE pattern, got %v", plan.Args[3])
+	}
+}
+
+func TestKeysetQuery_Cursor(t *testing.T) {
+	q := messagesQuery()
+	first, err := q.Build(PageRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ts := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	cursor, err := first.NextCursor(ts, 99)
+	if err != nil {
+		t.Fatalf("Failed to build cursor: %v", err)
+	}
+
+	next, err := q.Build(PageRequest{Cursor: cursor})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "SELECT id, status, created_at FROM messages WHERE device_id = $1 AND ((created_at < $2) OR (created_at = $2 AND id < $3)) ORDER BY created_at DESC, id DESC LIMIT 21"
+	if next.SQL != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, next.SQL)
+	}
+	if next.Args[1] != "2026-10-18T10:00:00Z" || next.Args[2] != "99" {
+		t.Errorf("Unexpected cursor args %v", next.Args)
+	}
+
+	// A cursor is bound to the sort order it was issued for.
+	_, err = q.Build(PageRequest{Cursor: cursor, Sort: []SortField{{Key: "status"}}})
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestKeysetPlan_NextCursorWithoutNextPage(t *testing.T) {
+	plan, err := messagesQuery().Build(PageRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var missing *time.Time
+	for _, values := range [][]any{nil, {nil, nil}, {missing, nil}} {
+		cursor, err := plan.NextCursor(values...)
+		if err != nil || cursor != "" {
+			t.Errorf("Expected an empty cursor for %v, got %q (err: %v)", values, cursor, err)
+		}
+	}
+
+	if _, err := plan.NextCursor(time.Now(), nil); err == nil {
+		t.Error("Expected an error for a partially nil cursor")
+	}
+}
+
+func TestKeysetQuery_Rejects(t *testing.T) {
+	q := messagesQuery()
+
+	tests := []struct {
+		name     string
+		req      PageRequest
+		expected error
+	}{
+		{"unknown sort", PageRequest{Sort: []SortField{{Key: "body"}}}, ErrInvalidSort},
+		{"unknown filter", PageRequest{Filters: []Filter{{Key: "body", Value: "x"}}}, ErrInvalidFilter},
+		{"disallowed op", PageRequest{Filters: []Filter{{Key: "status", Op: OpGt, Value: "x"}}}, ErrInvalidFilter},
+		{"bad value", PageRequest{Filters: []Filter{{Key: "id", Op: OpGt, Value: "abc"}}}, ErrInvalidFilter},
+		{"garbage cursor", PageRequest{Cursor: "!!!"}, ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := q.Build(tt.req)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+			if HTTPStatus(err) != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", HTTPStatus(err))
+			}
+		})
+	}
+}
+
+func TestTrimPage(t *testing.T) {
+	items, more := TrimPage([]int{1, 2, 3}, 2)
+	if len(items) != 2 || !more {
+		t.Errorf("Expected 2 items and more pages, got %v %v", items, more)
+	}
+
+	items, more = TrimPage([]int{1, 2}, 2)
+	if len(items) != 2 || more {
+		t.Errorf("Expected 2 items and no more pages, got %v %v", items, more)
+	}
+}
@@ -17,25 +17,27 @@ package postgres
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 )
 
+const defaultPingTimeout = 30 * time.Second
+
+// NewDatabase creates a pool and pings it. When cfg.StartupTimeout is set, failed
+// pings are retried with backoff until the budget runs out. Connection failures are
+// returned as *ConnectError; NewDatabase never exits the process.
 func NewDatabase(ctx context.Context, log *logrus.Logger, cfg Config) (*pgxpool.Pool, error) {
 	log.Info("Connecting to database...")
-	pgxCfg, err := pgxpool.ParseConfig(cfg.DSN)
+	pgxCfg, err := newPoolConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	pgxCfg.MinConns = cfg.MinConns
-	pgxCfg.MaxConns = cfg.MaxConns
-	pgxCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
-	pgxCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
-	pgxCfg.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
-
 	start := time.Now()
 	pool, err := pgxpool.NewWithConfig(ctx, pgxCfg)
 	if err != nil {
@@ -43,13 +45,118 @@ func NewDatabase(ctx context.Context, log *logrus.Logger, cfg Config) (*pgxpool.
 	}
 	log.WithField("duration", time.Since(start)).Debug("pgxpool initialized")
 
-	pingCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-	if err := pool.Ping(pingCtx); err != nil {
-		log.WithError(err).Fatal("database ping failed")
+	if err := pingWithRetry(ctx, log, pool, cfg); err != nil {
 		pool.Close()
 		return nil, err
 	}
 
 	return pool, nil
 }
+
+func pingWithRetry(ctx context.Context, log *logrus.Logger, pool *pgxpool.Pool, cfg Config) error {
+	ctx = WithoutTenant(ctx)
+	deadline := time.Now().Add(cfg.StartupTimeout)
+
+	for attempt := 1; ; attempt++ {
+		// No attempt may outlast the startup budget, however long a ping may take.
+		timeout := defaultPingTimeout
+		if cfg.StartupTimeout > 0 {
+			timeout = min(timeout, time.Until(deadline))
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := pool.Ping(pingCtx)
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				log.WithField("attempt", attempt).Info("database connection established")
+			}
+			return nil
+		}
+
+		connErr := newConnectError(err, attempt)
+		delay := cfg.StartupBackoff.Delay(attempt)
+		entry := log.WithError(err).WithFields(logrus.Fields{
+			"attempt": attempt,
+			"kind":    connErr.Kind,
+		})
+
+		if !connErr.Retryable() || ctx.Err() != nil || time.Now().Add(delay).After(deadline) {
+			entry.Error("database ping failed")
+			return connErr
+		}
+
+		entry.WithField("retry_in", delay).Warn("database ping failed, retrying")
+		if err := backoff.Sleep(ctx, delay); err != nil {
+			return connErr
+		}
+	}
+}
+
+func newPoolConfig(cfg Config) (*pgxpool.Config, error) {
+	pgxCfg, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zero values keep the pgx defaults; a zero HealthCheckPeriod would make pgxpool panic.
+	pgxCfg.MinConns = cfg.MinConns
+	if cfg.MaxConns > 0 {
+		pgxCfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		pgxCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		pgxCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.ConnectTimeout > 0 {
+		pgxCfg.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+
+	if cfg.MaxConnLifetime > 0 {
+		pgxCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnLifetimeJitter > 0 {
+		pgxCfg.MaxConnLifetimeJitter = cfg.MaxConnLifetimeJitter
+	}
+	if cfg.ApplicationName != "" {
+		pgxCfg.ConnConfig.RuntimeParams["application_name"] = cfg.ApplicationName
+	}
+
+	if err := applyTLS(pgxCfg, cfg.TLS); err != nil {
+		return nil, err
+	}
+	applyHooks(pgxCfg, cfg)
+
+	if cfg.TenantScoped || cfg.TenantSetting != "" {
+		newTenantHooks(cfg).install(pgxCfg)
+	}
+
+	return pgxCfg, nil
+}
+
+func applyHooks(pgxCfg *pgxpool.Config, cfg Config) {
+	if cfg.PasswordProvider != nil {
+		provider := cfg.PasswordProvider
+		pgxCfg.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			password, err := provider(ctx)
+			if err != nil {
+				return fmt.Errorf("postgres: password provider: %w", err)
+			}
+			cc.Password = password
+			return nil
+		}
+	}
+
+	if len(cfg.AfterConnect) > 0 {
+		hooks := cfg.AfterConnect
+		pgxCfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			for _, hook := range hooks {
+				if err := hook(ctx, conn); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 19.52
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Querier is the query surface shared by *pgxpool.Pool, pgx.Tx, *pgx.Conn and
+// *Cluster. Repositories should accept a Querier so they can run inside a
+// caller's transaction and be faked in tests.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	// Begin starts a transaction, or a savepoint when called on a pgx.Tx.
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+var (
+	_ Querier = (*pgxpool.Pool)(nil)
+	_ Querier = (pgx.Tx)(nil)
+	_ Querier = (*pgx.Conn)(nil)
+	_ Querier = (*Cluster)(nil)
+)
+
+// Exec runs sql and returns the number of affected rows.
+func Exec(ctx context.Context, q Querier, sql string, args ...any) (int64, error) {
+	tag, err := q.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, Classify(err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// QueryOne runs sql and scans the single resulting row into T. Structs are mapped
+// by column name using the same db tag rules as CopyFrom; other types scan the
+// first column. No rows yields ErrNotFound.
+func QueryOne[T any](ctx context.Context, q Querier, sql string, args ...any) (T, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		var zero T
+		return zero, Classify(err)
+	}
+
+	v, err := pgx.CollectOneRow(rows, rowTo[T]())
+	return v, Classify(err)
+}
+
+// QueryAll runs sql and scans every row into T, following the rules of QueryOne.
+// An empty result is an empty slice, not an error.
+func QueryAll[T any](ctx context.Context, q Querier, sql string, args ...any) ([]T, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, Classify(err)
+	}
+
+	v, err := pgx.CollectRows(rows, rowTo[T]())
+	return v, Classify(err)
+}
+
+func rowTo[T any]() pgx.RowToFunc[T] {
+	t := reflect.TypeFor[T]()
+	if t.Kind() == reflect.Struct && t != reflect.TypeFor[time.Time]() {
+		return rowToStruct[T]
+	}
+	return pgx.RowTo[T]
+}
+
+func rowToStruct[T any](row pgx.CollectableRow) (T, error) {
+	var v T
+
+	fields, err := structFields(reflect.TypeFor[T]())
+	if err != nil {
+		return v, err
+	}
+
+	rv := reflect.ValueOf(&v).Elem()
+	descs := row.FieldDescriptions()
+	dest := make([]any, len(descs))
+	for i, fd := range descs {
+		for _, f := range fields {
+			if f.column == fd.Name {
+				dest[i] = rv.FieldByIndex(f.index).Addr().Interface()
+				break
+			}
+		}
+		if dest[i] == nil {
+			return v, fmt.Errorf("postgres: no field in %T for column %q", v, fd.Name)
+		}
+	}
+
+	return v, row.Scan(dest...)
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 20.10
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeRow struct {
+	names  []string
+	values []any
+}
+
+func (r fakeRow) FieldDescriptions() []pgconn.FieldDescription {
+	fds := make([]pgconn.FieldDescription, len(r.names))
+	for i, n := range r.names {
+		fds[i] = pgconn.FieldDescription{Name: n}
+	}
+	return fds
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *string:
+			*d = r.values[i].(string)
+		case *int64:
+			*d = r.values[i].(int64)
+		case *time.Time:
+			*d = r.values[i].(time.Time)
+		default:
+			return errors.New("unsupported scan target")
+		}
+	}
+	return nil
+}
+
+func (r fakeRow) Values() ([]any, error) { return r.values, nil }
+func (r fakeRow) RawValues() [][]byte    { return nil }
+
+func TestRowToStruct(t *testing.T) {
+	now := time.Now()
+	row := fakeRow{
+		names:  []string{"phone", "created_at", "webhook_url"},
+		values: []any{"628123", now, "https://example.com"},
+	}
+
+	c, err := rowToStruct[contact](row)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Phone != "628123" || !c.CreatedAt.Equal(now) || c.WebhookURL != "https://example.com" {
+		t.Errorf("Expected fields to be scanned, got %+v", c)
+	}
+}
+
+func TestRowToStruct_UnknownColumn(t *testing.T) {
+	row := fakeRow{names: []string{"phone", "missing"}, values: []any{"628123", "x"}}
+
+	if _, err := rowToStruct[contact](row); err == nil {
+		t.Error("Expected error for unmapped column")
+	}
+}
+
+func TestQuerier_Integration(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database test: TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := NewDatabase(ctx, logrus.New(), Config{DSN: dsn, MaxConns: 2, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create database pool: %v", err)
+	}
+	defer pool.Close()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) //nolint:errcheck
+	}()
+
+	if _, err := Exec(ctx, tx, `CREATE TEMP TABLE querier_test (phone TEXT PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	n, err := Exec(ctx, tx, `INSERT INTO querier_test VALUES ('1', 'a'), ('2', 'b')`)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 rows inserted, got %d (%v)", n, err)
+	}
+
+	type row struct {
+		Phone string
+		Name  string
+	}
+
+	one, err := QueryOne[row](ctx, tx, `SELECT phone, name FROM querier_test WHERE phone = $1`, "2")
+	if err != nil || one.Name != "b" {
+		t.Errorf("Expected name b, got %+v (%v)", one, err)
+	}
+
+	all, err := QueryAll[row](ctx, tx, `SELECT phone, name FROM querier_test ORDER BY phone`)
+	if err != nil || len(all) != 2 {
+		t.Errorf("Expected 2 rows, got %d (%v)", len(all), err)
+	}
+
+	count, err := QueryOne[int64](ctx, tx, `SELECT count(*) FROM querier_test`)
+	if err != nil || count != 2 {
+		t.Errorf("Expected count 2, got %d (%v)", count, err)
+	}
+
+	if _, err := QueryOne[row](ctx, tx, `SELECT phone, name FROM querier_test WHERE phone = 'x'`); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 12.40
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres/queue
+ */
+
+// Package queue provides a durable job queue stored in Postgres. Workers claim
+// jobs with FOR UPDATE SKIP LOCKED, so any number of processes can share a queue.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/db/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultTable is the table used when NewClient is given an empty name.
+	DefaultTable = "jobs"
+	// DefaultQueue is the queue jobs are enqueued to when no queue is given.
+	DefaultQueue = "default"
+	// DefaultMaxAttempts is the number of attempts before a job is discarded.
+	DefaultMaxAttempts = 25
+)
+
+// Job states.
+const (
+	StateAvailable = "available"
+	StateRunning   = "running"
+	StateRetryable = "retryable"
+	StateCompleted = "completed"
+	StateDiscarded = "discarded"
+)
+
+// ErrDuplicateJob is returned by Enqueue when a job with the same kind and unique
+// key is still pending or running.
+var ErrDuplicateJob = errors.New("queue: duplicate unique job")
+
+const tableDDL = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+	id           BIGSERIAL PRIMARY KEY,
+	kind         TEXT        NOT NULL,
+	queue        TEXT        NOT NULL DEFAULT 'default',
+	args         JSONB       NOT NULL,
+	priority     SMALLINT    NOT NULL DEFAULT 0,
+	state        TEXT        NOT NULL DEFAULT 'available',
+	attempts     INT         NOT NULL DEFAULT 0,
+	max_attempts INT         NOT NULL DEFAULT 25,
+	run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+	unique_key   TEXT,
+	last_error   TEXT,
+	locked_at    TIMESTAMPTZ,
+	created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	finished_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (queue, priority DESC, run_at, id)
+	WHERE state IN ('available', 'retryable');
+CREATE UNIQUE INDEX IF NOT EXISTS %[3]s ON %[1]s (kind, unique_key)
+	WHERE unique_key IS NOT NULL AND state IN ('available', 'running', 'retryable');
+CREATE INDEX IF NOT EXISTS %[4]s ON %[1]s (state, finished_at)
+	WHERE state IN ('completed', 'discarded');`
+
+// Args is implemented by job argument structs. Kind identifies the handler that
+// processes the job and must be stable across deploys.
+type Args interface {
+	Kind() string
+}
+
+// EnqueueOptions controls how a job is scheduled. The zero value enqueues to
+// DefaultQueue with priority 0, runs immediately and allows DefaultMaxAttempts.
+type EnqueueOptions struct {
+	Queue       string
+	Priority    int16 // higher runs first
+	RunAt       time.Time
+	MaxAttempts int
+	// UniqueKey, when set, rejects the job with ErrDuplicateJob while another job of
+	// the same kind and key is pending or running.
+	UniqueKey string
+}
+
+// Client enqueues jobs and manages the jobs table.
+type Client struct {
+	pool  *pgxpool.Pool
+	table string
+}
+
+// NewClient returns a Client that stores jobs in table, or DefaultTable when table is empty.
+func NewClient(pool *pgxpool.Pool, table string) *Client {
+	if table == "" {
+		table = DefaultTable
+	}
+	return &Client{pool: pool, table: table}
+}
+
+func (c *Client) ident() string {
+	return pgx.Identifier{c.table}.Sanitize()
+}
+
+// CreateTable creates the jobs table and its indexes if they do not exist.
+func (c *Client) CreateTable(ctx context.Context) error {
+	_, err := c.pool.Exec(postgres.WithoutTenant(ctx), fmt.Sprintf(tableDDL,
+		c.ident(),
+		pgx.Identifier{c.table + "_fetch_idx"}.Sanitize(),
+		pgx.Identifier{c.table + "_unique_idx"}.Sanitize(),
+		pgx.Identifier{c.table + "_finished_idx"}.Sanitize(),
+	))
+	return err
+}
+
+// Enqueue inserts a job outside of any caller transaction and returns its ID.
+func (c *Client) Enqueue(ctx context.Context, args Args, opts *EnqueueOptions) (int64, error) {
+	return c.enqueue(ctx, c.pool, args, opts)
+}
+
+// EnqueueTx inserts a job within tx, so it only becomes visible to workers if tx commits.
+func (c *Client) EnqueueTx(ctx context.Context, tx pgx.Tx, args Args, opts *EnqueueOptions) (int64, error) {
+	return c.enqueue(ctx, tx, args, opts)
+}
+
+func (c *Client) enqueue(ctx context.Context, db postgres.Querier, args Args, opts *EnqueueOptions) (int64, error) {
+	if opts == nil {
+		opts = &EnqueueOptions{}
+	}
+
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return 0, fmt.Errorf("queue: marshal %s args: %w", args.Kind(), err)
+	}
+
+	queue := opts.Queue
+	if queue == "" {
+		queue = DefaultQueue
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	var uniqueKey *string
+	if opts.UniqueKey != "" {
+		uniqueKey = &opts.UniqueKey
+	}
+
+	var id int64
+	err = db.QueryRow(ctx, fmt.Sprintf(`
+		INSERT INTO %s (kind, queue, args, priority, run_at, max_attempts, unique_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (kind, unique_key)
+			WHERE unique_key IS NOT NULL AND state IN ('available', 'running', 'retryable')
+			DO NOTHING
+		RETURNING id`, c.ident()),
+		args.Kind(), queue, payload, opts.Priority, runAt, maxAttempts, uniqueKey,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrDuplicateJob
+	}
+
+	return id, err
+}
+
+// CleanupPolicy controls how long finished jobs are kept and when stuck jobs are
+// returned to the queue.
+type CleanupPolicy struct {
+	CompletedRetention time.Duration // default 24h
+	DiscardedRetention time.Duration // default 7 days
+	// RescueAfter returns jobs that have been running longer than this to the queue,
+	// e.g. after a worker crashed. Default 1h.
+	RescueAfter time.Duration
+	Interval    time.Duration // how often RunCleaner runs, default 5m
+}
+
+func (p CleanupPolicy) withDefaults() CleanupPolicy {
+	if p.CompletedRetention <= 0 {
+		p.CompletedRetention = 24 * time.Hour
+	}
+	if p.DiscardedRetention <= 0 {
+		p.DiscardedRetention = 7 * 24 * time.Hour
+	}
+	if p.RescueAfter <= 0 {
+		p.RescueAfter = time.Hour
+	}
+	if p.Interval <= 0 {
+		p.Interval = 5 * time.Minute
+	}
+	return p
+}
+
+// Cleanup deletes finished jobs past their retention and rescues stuck jobs.
+// It returns the number of deleted and rescued rows.
+func (c *Client) Cleanup(ctx context.Context, policy CleanupPolicy) (deleted, rescued int64, err error) {
+	policy = policy.withDefaults()
+	ctx = postgres.WithoutTenant(ctx)
+
+	var tag pgconn.CommandTag
+	tag, err = c.pool.Exec(ctx, fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE (state = 'completed' AND finished_at < now() - $1::interval)
+		   OR (state = 'discarded' AND finished_at < now() - $2::interval)`, c.ident()),
+		policy.CompletedRetention, policy.DiscardedRetention,
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	deleted = tag.RowsAffected()
+
+	tag, err = c.pool.Exec(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET state = CASE WHEN attempts >= max_attempts THEN 'discarded' ELSE 'retryable' END,
+			finished_at = CASE WHEN attempts >= max_attempts THEN now() END,
+			last_error = 'rescued: job exceeded running time',
+			run_at = now(), locked_at = NULL
+		WHERE state = 'running' AND locked_at < now() - $1::interval`, c.ident()),
+		policy.RescueAfter,
+	)
+	if err != nil {
+		return deleted, 0, err
+	}
+
+	return deleted, tag.RowsAffected(), nil
+}
@@ -0,0 +1,267 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 13.52
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres/queue
+ */
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/db/postgres"
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/sirupsen/logrus"
+)
+
+type downloadMedia struct {
+	MessageID string `json:"message_id"`
+}
+
+func (downloadMedia) Kind() string { return "download_media" }
+
+func TestRegister_DuplicateKindPanics(t *testing.T) {
+	w := NewWorker(NewClient(nil, ""), logrus.New(), WorkerConfig{})
+	Register(w, func(_ context.Context, _ *Job[downloadMedia]) error { return nil })
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic on duplicate registration")
+		}
+	}()
+	Register(w, func(_ context.Context, _ *Job[downloadMedia]) error { return nil })
+}
+
+type sendReceipt struct {
+	ID string `json:"id"`
+}
+
+func (r *sendReceipt) Kind() string { return "send_receipt" }
+
+func TestRegister_PointerArgs(t *testing.T) {
+	w := NewWorker(NewClient(nil, ""), logrus.New(), WorkerConfig{})
+
+	var got *Job[*sendReceipt]
+	Register(w, func(_ context.Context, job *Job[*sendReceipt]) error {
+		got = job
+		return nil
+	})
+
+	if err := w.run(context.Background(), &jobRow{kind: "send_receipt", args: []byte(`{"id":"R1"}`)}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got == nil || got.Args == nil || got.Args.ID != "R1" {
+		t.Errorf("Unexpected job: %+v", got)
+	}
+}
+
+func TestWorker_JobContextFollowsRun(t *testing.T) {
+	w := NewWorker(NewClient(nil, ""), logrus.New(), WorkerConfig{JobTimeout: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jobCtx, jobCancel := w.jobContext(ctx)
+	defer jobCancel()
+
+	if _, ok := jobCtx.Deadline(); !ok {
+		t.Error("Expected JobTimeout to set a deadline")
+	}
+	cancel()
+	if !errors.Is(jobCtx.Err(), context.Canceled) {
+		t.Errorf("Expected canceling Run's context to cancel the job, got %v", jobCtx.Err())
+	}
+}
+
+func TestWorker_RunDecodesArgs(t *testing.T) {
+	w := NewWorker(NewClient(nil, ""), logrus.New(), WorkerConfig{})
+
+	var got *Job[downloadMedia]
+	Register(w, func(_ context.Context, job *Job[downloadMedia]) error {
+		got = job
+		return nil
+	})
+
+	row := &jobRow{id: 7, kind: "download_media", args: []byte(`{"message_id":"ABC"}`), attempt: 2}
+	if err := w.run(context.Background(), row); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got == nil || got.ID != 7 || got.Attempt != 2 || got.Args.MessageID != "ABC" {
+		t.Errorf("Unexpected job: %+v", got)
+	}
+}
+
+func TestWorker_RunDiscardsUnknownAndInvalid(t *testing.T) {
+	w := NewWorker(NewClient(nil, ""), logrus.New(), WorkerConfig{})
+	Register(w, func(_ context.Context, _ *Job[downloadMedia]) error { return nil })
+
+	var discard *discardError
+
+	err := w.run(context.Background(), &jobRow{kind: "unknown"})
+	if !errors.As(err, &discard) {
+		t.Errorf("Expected discard error for unknown kind, got %v", err)
+	}
+
+	err = w.run(context.Background(), &jobRow{kind: "download_media", args: []byte("not json")})
+	if !errors.As(err, &discard) {
+		t.Errorf("Expected discard error for invalid args, got %v", err)
+	}
+}
+
+func TestWorker_RunRecoversPanic(t *testing.T) {
+	w := NewWorker(NewClient(nil, ""), logrus.New(), WorkerConfig{})
+	Register(w, func(_ context.Context, _ *Job[downloadMedia]) error { panic("boom") })
+
+	if err := w.run(context.Background(), &jobRow{kind: "download_media", args: []byte("{}")}); err == nil {
+		t.Error("Expected panic to be returned as error")
+	}
+}
+
+func TestDiscard_Unwrap(t *testing.T) {
+	base := errors.New("invalid phone number")
+	if err := Discard(base); !errors.Is(err, base) {
+		t.Error("Expected Discard to wrap the original error")
+	}
+}
+
+func TestCleanupPolicy_Defaults(t *testing.T) {
+	p := CleanupPolicy{}.withDefaults()
+
+	if p.CompletedRetention != 24*time.Hour {
+		t.Errorf("Expected CompletedRetention 24h, got %v", p.CompletedRetention)
+	}
+	if p.DiscardedRetention != 7*24*time.Hour {
+		t.Errorf("Expected DiscardedRetention 7d, got %v", p.DiscardedRetention)
+	}
+	if p.RescueAfter != time.Hour {
+		t.Errorf("Expected RescueAfter 1h, got %v", p.RescueAfter)
+	}
+}
+
+func TestQueue_Integration(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database test: TEST_DATABASE_URL not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := postgres.NewDatabase(ctx, logrus.New(), postgres.Config{DSN: dsn, MaxConns: 4, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create database pool: %v", err)
+	}
+	defer pool.Close()
+
+	client := NewClient(pool, "jobs_test")
+	if err := client.CreateTable(ctx); err != nil {
+		t.Fatalf("Failed to create jobs table: %v", err)
+	}
+	defer func() {
+		_, _ = pool.Exec(context.Background(), "DROP TABLE jobs_test") //nolint:errcheck
+	}()
+
+	if _, err := client.Enqueue(ctx, downloadMedia{MessageID: "A"}, &EnqueueOptions{UniqueKey: "A"}); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	if _, err := client.Enqueue(ctx, downloadMedia{MessageID: "A"}, &EnqueueOptions{UniqueKey: "A"}); !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("Expected ErrDuplicateJob, got %v", err)
+	}
+
+	done := make(chan string, 1)
+	w := NewWorker(client, logrus.New(), WorkerConfig{PollInterval: 50 * time.Millisecond, Backoff: backoff.Policy{Initial: time.Millisecond}})
+	Register(w, func(_ context.Context, job *Job[downloadMedia]) error {
+		if job.Attempt == 1 {
+			return errors.New("transient")
+		}
+		done <- job.Args.MessageID
+		return nil
+	})
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go func() {
+		_ = w.Run(runCtx) //nolint:errcheck
+	}()
+
+	select {
+	case id := <-done:
+		if id != "A" {
+			t.Errorf("Expected job A, got %s", id)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for job")
+	}
+}
+
+func TestWorker_FinishRequiresOwnership(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database test: TEST_DATABASE_URL not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := postgres.NewDatabase(ctx, logrus.New(), postgres.Config{DSN: dsn, MaxConns: 4, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create database pool: %v", err)
+	}
+	defer pool.Close()
+
+	client := NewClient(pool, "jobs_lost_test")
+	if err := client.CreateTable(ctx); err != nil {
+		t.Fatalf("Failed to create jobs table: %v", err)
+	}
+	defer func() {
+		_, _ = pool.Exec(context.Background(), "DROP TABLE jobs_lost_test") //nolint:errcheck
+	}()
+
+	if _, err := client.Enqueue(ctx, downloadMedia{MessageID: "A"}, nil); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	w := NewWorker(client, logrus.New(), WorkerConfig{})
+	Register(w, func(_ context.Context, _ *Job[downloadMedia]) error { return nil })
+
+	stalled, err := w.claim(ctx, []string{"download_media"}, 1)
+	if err != nil || len(stalled) != 1 {
+		t.Fatalf("Expected to claim the job, got %d (%v)", len(stalled), err)
+	}
+
+	// The first worker stalls past RescueAfter and the job is claimed again.
+	if _, err := pool.Exec(ctx, "UPDATE jobs_lost_test SET locked_at = now() - interval '2 hours'"); err != nil {
+		t.Fatalf("Failed to age the job: %v", err)
+	}
+	if _, rescued, err := client.Cleanup(ctx, CleanupPolicy{RescueAfter: time.Hour}); err != nil || rescued != 1 {
+		t.Fatalf("Expected the job to be rescued, got %d (%v)", rescued, err)
+	}
+	current, err := w.claim(ctx, []string{"download_media"}, 1)
+	if err != nil || len(current) != 1 || current[0].attempt != 2 {
+		t.Fatalf("Expected the job claimed again on attempt 2, got %v (%v)", current, err)
+	}
+
+	const failed = `state = 'retryable', locked_at = NULL, last_error = $3`
+	if err := w.finish(ctx, stalled[0], failed, "late failure"); !errors.Is(err, errJobLost) {
+		t.Errorf("Expected the stalled worker to have lost the job, got %v", err)
+	}
+	if err := w.finish(ctx, current[0], `state = 'completed', finished_at = now(), locked_at = NULL`); err != nil {
+		t.Errorf("Expected the current worker to record its result, got %v", err)
+	}
+
+	var state string
+	if err := pool.QueryRow(ctx, "SELECT state FROM jobs_lost_test").Scan(&state); err != nil || state != "completed" {
+		t.Errorf("Expected the job completed, got %q (%v)", state, err)
+	}
+}
@@ -0,0 +1,336 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 13.15
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres/queue
+ */
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/db/postgres"
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Job is a claimed job with its decoded arguments.
+type Job[T Args] struct {
+	ID          int64
+	Kind        string
+	Queue       string
+	Priority    int16
+	Attempt     int
+	MaxAttempts int
+	CreatedAt   time.Time
+	Args        T
+}
+
+type discardError struct {
+	err error
+}
+
+func (e *discardError) Error() string { return e.err.Error() }
+func (e *discardError) Unwrap() error { return e.err }
+
+// Discard wraps err so the job is discarded instead of retried.
+func Discard(err error) error {
+	return &discardError{err: err}
+}
+
+// errJobLost reports a job that was rescued and claimed again while its first
+// worker was still running it, so the first worker must not record its outcome.
+var errJobLost = errors.New("queue: job no longer owned by this worker")
+
+type jobRow struct {
+	id          int64
+	kind        string
+	queue       string
+	args        []byte
+	priority    int16
+	attempt     int
+	maxAttempts int
+	createdAt   time.Time
+}
+
+type handler func(ctx context.Context, row *jobRow) error
+
+// Register adds fn as the handler for jobs whose arguments are of type T.
+// It must be called before Run and panics if the kind is already registered.
+func Register[T Args](w *Worker, fn func(ctx context.Context, job *Job[T]) error) {
+	kind := kindOf[T]()
+	if _, ok := w.handlers[kind]; ok {
+		panic(fmt.Sprintf("queue: handler for %q already registered", kind))
+	}
+
+	w.handlers[kind] = func(ctx context.Context, row *jobRow) error {
+		var args T
+		if err := json.Unmarshal(row.args, &args); err != nil {
+			return Discard(fmt.Errorf("decode args: %w", err))
+		}
+
+		return fn(ctx, &Job[T]{
+			ID:          row.id,
+			Kind:        row.kind,
+			Queue:       row.queue,
+			Priority:    row.priority,
+			Attempt:     row.attempt,
+			MaxAttempts: row.maxAttempts,
+			CreatedAt:   row.createdAt,
+			Args:        args,
+		})
+	}
+}
+
+// kindOf returns the kind of T without calling Kind on a nil pointer when T is a
+// pointer type.
+func kindOf[T Args]() string {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() == reflect.Pointer {
+		return reflect.New(typ.Elem()).Interface().(Args).Kind()
+	}
+	var zero T
+	return zero.Kind()
+}
+
+// WorkerConfig holds the configuration for a Worker.
+type WorkerConfig struct {
+	Queue        string        // queue to work, default DefaultQueue
+	Concurrency  int           // jobs run in parallel, default 10
+	PollInterval time.Duration // wait when the queue is empty, default 1s
+	JobTimeout   time.Duration // per-job deadline, zero for none
+	Backoff      backoff.Policy
+}
+
+// Worker claims jobs from one queue and runs their handlers.
+type Worker struct {
+	client   *Client
+	log      *logrus.Logger
+	cfg      WorkerConfig
+	handlers map[string]handler
+}
+
+// NewWorker returns a Worker for client. Register handlers before calling Run.
+func NewWorker(client *Client, log *logrus.Logger, cfg WorkerConfig) *Worker {
+	if cfg.Queue == "" {
+		cfg.Queue = DefaultQueue
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 10
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+
+	return &Worker{
+		client:   client,
+		log:      log,
+		cfg:      cfg,
+		handlers: make(map[string]handler),
+	}
+}
+
+// Run claims and processes jobs until ctx is canceled, then waits for running jobs
+// to finish. Canceling ctx cancels the context of running jobs too; their outcome
+// is still recorded.
+func (w *Worker) Run(ctx context.Context) error {
+	if len(w.handlers) == 0 {
+		return errors.New("queue: no handlers registered")
+	}
+
+	kinds := make([]string, 0, len(w.handlers))
+	for k := range w.handlers {
+		kinds = append(kinds, k)
+	}
+
+	slots := make(chan struct{}, w.cfg.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	w.log.WithFields(logrus.Fields{
+		"queue":       w.cfg.Queue,
+		"concurrency": w.cfg.Concurrency,
+	}).Info("Job worker started")
+
+	for {
+		free := w.cfg.Concurrency - len(slots)
+		var rows []*jobRow
+		if free > 0 {
+			var err error
+			rows, err = w.claim(ctx, kinds, free)
+			if err != nil && ctx.Err() == nil {
+				w.log.WithError(err).Error("failed to claim jobs")
+			}
+		}
+
+		for _, row := range rows {
+			slots <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				w.work(ctx, row)
+			}()
+		}
+
+		if len(rows) > 0 {
+			continue
+		}
+
+		if backoff.Sleep(ctx, w.cfg.PollInterval) != nil {
+			w.log.WithField("queue", w.cfg.Queue).Info("Job worker stopping")
+			return nil
+		}
+	}
+}
+
+func (w *Worker) claim(ctx context.Context, kinds []string, limit int) ([]*jobRow, error) {
+	rows, err := w.client.pool.Query(postgres.WithoutTenant(ctx), fmt.Sprintf(`
+		UPDATE %[1]s SET state = 'running', attempts = attempts + 1, locked_at = now()
+		WHERE id IN (
+			SELECT id FROM %[1]s
+			WHERE queue = $1 AND kind = ANY($2)
+			  AND state IN ('available', 'retryable') AND run_at <= now()
+			ORDER BY priority DESC, run_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, queue, args, priority, attempts, max_attempts, created_at`, w.client.ident()),
+		w.cfg.Queue, kinds, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*jobRow, error) {
+		var j jobRow
+		err := row.Scan(&j.id, &j.kind, &j.queue, &j.args, &j.priority, &j.attempt, &j.maxAttempts, &j.createdAt)
+		return &j, err
+	})
+}
+
+// jobContext returns the context a job runs with: ctx, bounded by JobTimeout.
+func (w *Worker) jobContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if w.cfg.JobTimeout > 0 {
+		return context.WithTimeout(ctx, w.cfg.JobTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (w *Worker) work(ctx context.Context, row *jobRow) {
+	jobCtx, cancel := w.jobContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	err := w.run(jobCtx, row)
+
+	// The outcome is recorded even when ctx was canceled during the job.
+	ctx = context.WithoutCancel(ctx)
+
+	entry := w.log.WithFields(logrus.Fields{
+		"job_id":   row.id,
+		"kind":     row.kind,
+		"attempt":  row.attempt,
+		"duration": time.Since(start),
+	})
+
+	if err == nil {
+		entry.Debug("job completed")
+		w.record(ctx, entry, row, `state = 'completed', finished_at = now(), locked_at = NULL, last_error = NULL`)
+		return
+	}
+
+	var discard *discardError
+	if errors.As(err, &discard) || row.attempt >= row.maxAttempts {
+		entry.WithError(err).Error("job discarded")
+		w.record(ctx, entry, row, `state = 'discarded', finished_at = now(), locked_at = NULL, last_error = $3`, err.Error())
+		return
+	}
+
+	delay := w.cfg.Backoff.Delay(row.attempt)
+	entry.WithError(err).WithField("retry_in", delay).Warn("job failed, scheduling retry")
+	w.record(ctx, entry, row, `state = 'retryable', run_at = now() + $4::interval, locked_at = NULL, last_error = $3`, err.Error(), delay)
+}
+
+func (w *Worker) run(ctx context.Context, row *jobRow) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	h, ok := w.handlers[row.kind]
+	if !ok {
+		return Discard(fmt.Errorf("no handler for kind %q", row.kind))
+	}
+
+	return h(ctx, row)
+}
+
+func (w *Worker) record(ctx context.Context, entry *logrus.Entry, row *jobRow, set string, args ...any) {
+	err := w.finish(ctx, row, set, args...)
+	if errors.Is(err, errJobLost) {
+		entry.Warn("job result dropped, the job was rescued and claimed again")
+	} else if err != nil {
+		entry.WithError(err).Error("failed to record job result")
+	}
+}
+
+// finish applies set, whose arguments start at $3, to the job of row while this
+// worker still owns it: the job is running the attempt row claimed. A job rescued
+// and claimed by another worker since yields errJobLost and is left alone.
+func (w *Worker) finish(ctx context.Context, row *jobRow, set string, args ...any) error {
+	ctx, cancel := context.WithTimeout(postgres.WithoutTenant(ctx), 10*time.Second)
+	defer cancel()
+
+	tag, err := w.client.pool.Exec(ctx, fmt.Sprintf(
+		`UPDATE %s SET %s WHERE id = $1 AND state = 'running' AND attempts = $2`, w.client.ident(), set),
+		append([]any{row.id, row.attempt}, args...)...,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errJobLost
+	}
+	return nil
+}
+
+// RunCleaner applies policy every policy.Interval until ctx is canceled.
+func (c *Client) RunCleaner(ctx context.Context, log *logrus.Logger, policy CleanupPolicy) {
+	policy = policy.withDefaults()
+
+	for {
+		deleted, rescued, err := c.Cleanup(ctx, policy)
+		if err != nil && ctx.Err() == nil {
+			log.WithError(err).Error("job cleanup failed")
+		} else if deleted > 0 || rescued > 0 {
+			log.WithFields(logrus.Fields{
+				"deleted": deleted,
+				"rescued": rescued,
+			}).Info("job cleanup finished")
+		}
+
+		if backoff.Sleep(ctx, policy.Interval) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 15.36
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultTenantSetting is the session setting RLS policies read the tenant from,
+// e.g. USING (tenant_id = current_setting('app.tenant_id', true)).
+const DefaultTenantSetting = "app.tenant_id"
+
+// tenantResetTimeout bounds the reset run when a connection is released, so a
+// stalled connection is destroyed instead of blocking the pool.
+const tenantResetTimeout = 5 * time.Second
+
+// ErrNoTenant is returned when a tenant-scoped pool is used without a tenant in the context.
+var ErrNoTenant = errors.New("postgres: no tenant in context")
+
+type tenantKey struct{}
+
+type tenantBypassKey struct{}
+
+// WithTenant returns a context whose queries run with tenantID as the RLS tenant.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantID returns the tenant stored by WithTenant.
+func TenantID(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithoutTenant marks ctx as a deliberate cross-tenant operation, such as a
+// migration or a background relay, so a tenant-scoped pool does not reject it.
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantBypassKey{}, true)
+}
+
+func tenantBypassed(ctx context.Context) bool {
+	v, _ := ctx.Value(tenantBypassKey{}).(bool) //nolint:errcheck
+	return v
+}
+
+// tenantHooks sets the tenant session settings when a connection is acquired and
+// resets them when it is released.
+type tenantHooks struct {
+	scoped  bool
+	setting string
+	role    string
+	active  sync.Map // *pgx.Conn -> struct{}
+}
+
+func newTenantHooks(cfg Config) *tenantHooks {
+	setting := cfg.TenantSetting
+	if setting == "" {
+		setting = DefaultTenantSetting
+	}
+	return &tenantHooks{scoped: cfg.TenantScoped, setting: setting, role: cfg.TenantRole}
+}
+
+func (h *tenantHooks) install(pgxCfg *pgxpool.Config) {
+	pgxCfg.PrepareConn = h.prepare
+	pgxCfg.AfterRelease = h.release
+	pgxCfg.BeforeClose = func(conn *pgx.Conn) {
+		h.active.Delete(conn)
+	}
+}
+
+func (h *tenantHooks) prepare(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	tenantID, ok := TenantID(ctx)
+	if !ok {
+		if h.scoped && !tenantBypassed(ctx) {
+			return true, ErrNoTenant
+		}
+		return true, nil
+	}
+
+	role := h.role
+	if role == "" {
+		role = "none"
+	}
+	if _, err := conn.Exec(ctx, "SELECT set_config($1, $2, false), set_config('role', $3, false)", h.setting, tenantID, role); err != nil {
+		return false, err
+	}
+	h.active.Store(conn, struct{}{})
+
+	return true, nil
+}
+
+func (h *tenantHooks) release(conn *pgx.Conn) bool {
+	if _, ok := h.active.LoadAndDelete(conn); !ok {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tenantResetTimeout)
+	defer cancel()
+
+	_, err := conn.Exec(ctx, "SELECT set_config($1, '', false), set_config('role', 'none', false)", h.setting)
+	return err == nil
+}
+
+// BeginTenantTx starts a transaction on pool and applies the tenant from ctx with
+// SET LOCAL semantics, so the setting ends with the transaction. Use it where
+// session state cannot be relied on, e.g. behind a transaction-pooling proxy.
+func BeginTenantTx(ctx context.Context, pool *pgxpool.Pool, cfg Config) (pgx.Tx, error) {
+	tenantID, ok := TenantID(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	h := newTenantHooks(cfg)
+	sql := "SELECT set_config($1, $2, true)"
+	args := []any{h.setting, tenantID}
+	if h.role != "" {
+		sql = "SELECT set_config($1, $2, true), set_config('role', $3, true)"
+		args = append(args, h.role)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		_ = tx.Rollback(ctx) //nolint:errcheck
+		return nil, err
+	}
+
+	return tx, nil
+}
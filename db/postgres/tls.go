@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 20.35
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/db/postgres
+ */
+
+package postgres
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TLSMode mirrors the libpq sslmode values that can be set explicitly.
+type TLSMode string
+
+// Supported TLS modes.
+const (
+	TLSDisable    TLSMode = "disable"
+	TLSRequire    TLSMode = "require"     // encrypt, do not verify the server
+	TLSVerifyCA   TLSMode = "verify-ca"   // verify the chain, not the host name
+	TLSVerifyFull TLSMode = "verify-full" // verify the chain and the host name
+)
+
+// TLSConfig configures the connection's TLS explicitly instead of through the DSN.
+// An empty Mode keeps whatever the DSN specifies.
+type TLSConfig struct {
+	Mode       TLSMode
+	CAFile     string // PEM bundle of trusted roots, default the system pool
+	CertFile   string // client certificate for certificate authentication
+	KeyFile    string
+	ServerName string // overrides the host name verified in verify-full
+}
+
+func (c TLSConfig) build(host string) (*tls.Config, error) {
+	if c.Mode == TLSDisable {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+	if tlsCfg.ServerName == "" {
+		tlsCfg.ServerName = host
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("postgres: read CA file: %w", err)
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("postgres: no certificates in %s", c.CAFile)
+		}
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("postgres: load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch c.Mode {
+	case TLSRequire:
+		tlsCfg.InsecureSkipVerify = true
+	case TLSVerifyCA:
+		// The standard verifier always checks the host name, so the chain is
+		// verified by hand.
+		tlsCfg.InsecureSkipVerify = true
+		roots := tlsCfg.RootCAs
+		tlsCfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("postgres: server presented no certificate")
+			}
+			opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	case TLSVerifyFull:
+		// Verified by crypto/tls against RootCAs and ServerName.
+	default:
+		return nil, fmt.Errorf("postgres: unknown TLS mode %q", c.Mode)
+	}
+
+	return tlsCfg, nil
+}
+
+func applyTLS(pgxCfg *pgxpool.Config, c TLSConfig) error {
+	if c.Mode == "" {
+		return nil
+	}
+
+	cc := pgxCfg.ConnConfig
+	tlsCfg, err := c.build(cc.Host)
+	if err != nil {
+		return err
+	}
+	cc.TLSConfig = tlsCfg
+
+	// Replace the DSN's sslmode fallbacks so the explicit mode is the only one
+	// tried, keeping one entry per additional host.
+	seen := map[string]bool{fmt.Sprintf("%s:%d", cc.Host, cc.Port): true}
+	var fallbacks []*pgconn.FallbackConfig
+	for _, fb := range cc.Fallbacks {
+		key := fmt.Sprintf("%s:%d", fb.Host, fb.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		fbTLS, err := c.build(fb.Host)
+		if err != nil {
+			return err
+		}
+		fallbacks = append(fallbacks, &pgconn.FallbackConfig{Host: fb.Host, Port: fb.Port, TLSConfig: fbTLS})
+	}
+	cc.Fallbacks = fallbacks
+
+	return nil
+}
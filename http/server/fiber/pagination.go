@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 18.40
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/http/server/fiber
+ */
+
+package httpserver
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PakaiWA/pakaiwa-platform/pagination"
+	"github.com/gofiber/fiber/v3"
+)
+
+// PageResponse is the JSON envelope for keyset-paginated list endpoints.
+type PageResponse[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// ParsePageRequest reads limit, cursor and sort from the query string, and a
+// filter from every parameter whose key is in filterable: "status=active" or
+// "created_at[gte]=2026-01-01". Other parameters are ignored. Sort is a
+// comma-separated list of keys, "-" prefixed for descending order. Pass
+// postgres.KeysetQuery.FilterKeys as filterable; KeysetQuery.Build validates the
+// rest of the request.
+func ParsePageRequest(c fiber.Ctx, filterable ...string) (pagination.PageRequest, error) {
+	var req pagination.PageRequest
+
+	for key, value := range c.Queries() {
+		switch key {
+		case "limit":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				return req, fiber.NewError(fiber.StatusBadRequest, "invalid limit")
+			}
+			req.Limit = limit
+
+		case "cursor":
+			req.Cursor = value
+
+		case "sort":
+			for _, part := range strings.Split(value, ",") {
+				part = strings.TrimSpace(part)
+				if part == "" {
+					continue
+				}
+				desc := strings.HasPrefix(part, "-")
+				req.Sort = append(req.Sort, pagination.SortField{
+					Key:  strings.TrimPrefix(part, "-"),
+					Desc: desc,
+				})
+			}
+
+		default:
+			filter := pagination.Filter{Key: key, Value: value}
+			if open := strings.IndexByte(key, '['); open > 0 && strings.HasSuffix(key, "]") {
+				filter.Key = key[:open]
+				filter.Op = pagination.FilterOp(key[open+1 : len(key)-1])
+			}
+			if slices.Contains(filterable, filter.Key) {
+				req.Filters = append(req.Filters, filter)
+			}
+		}
+	}
+
+	// Query parameters come from a map; keep the filter order stable.
+	slices.SortFunc(req.Filters, func(a, b pagination.Filter) int {
+		return strings.Compare(a.Key+string(a.Op), b.Key+string(b.Op))
+	})
+
+	return req, nil
+}
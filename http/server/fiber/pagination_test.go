@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 19.30
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/http/server/fiber
+ */
+
+package httpserver
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PakaiWA/pakaiwa-platform/pagination"
+	"github.com/gofiber/fiber/v3"
+)
+
+func parse(t *testing.T, target string) (pagination.PageRequest, int) {
+	t.Helper()
+
+	var req pagination.PageRequest
+	app := fiber.New()
+	app.Get("/messages", func(c fiber.Ctx) error {
+		var err error
+		req, err = ParsePageRequest(c, "status", "device_id")
+		if err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck
+	}()
+
+	return req, resp.StatusCode
+}
+
+func TestParsePageRequest(t *testing.T) {
+	req, status := parse(t, "/messages?limit=10&cursor=abc&sort=-created_at,status&status[in]=sent,read&device_id=d1&_=1697000000&fields=id")
+	if status != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+
+	if req.Limit != 10 || req.Cursor != "abc" {
+		t.Errorf("Unexpected limit/cursor: %+v", req)
+	}
+
+	if len(req.Sort) != 2 || req.Sort[0] != (pagination.SortField{Key: "created_at", Desc: true}) || req.Sort[1] != (pagination.SortField{Key: "status"}) {
+		t.Errorf("Unexpected sort: %+v", req.Sort)
+	}
+
+	expected := []pagination.Filter{
+		{Key: "device_id", Value: "d1"},
+		{Key: "status", Op: pagination.OpIn, Value: "sent,read"},
+	}
+	if len(req.Filters) != len(expected) {
+		t.Fatalf("Expected %d filters, got %+v", len(expected), req.Filters)
+	}
+	for i := range expected {
+		if req.Filters[i] != expected[i] {
+			t.Errorf("Filter %d: expected %+v, got %+v", i, expected[i], req.Filters[i])
+		}
+	}
+}
+
+func TestParsePageRequest_InvalidLimit(t *testing.T) {
+	if _, status := parse(t, "/messages?limit=abc"); status != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", status)
+	}
+}
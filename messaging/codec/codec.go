@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 21.10
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/codec
+ */
+
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Codec encodes events into message values and back. Its ContentType is sent in
+// the content_type header so consumers can pick the matching Codec.
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = make(map[string]Codec)
+)
+
+func init() {
+	Register(JSON{})
+	Register(Msgpack{})
+	Register(Protobuf{})
+}
+
+// Register makes c available to Lookup under its content type, replacing any
+// codec registered for it before.
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	codecs[c.ContentType()] = c
+}
+
+// ErrUnknownContentType reports a content type no codec is registered for. It
+// points at a missing Register call rather than a bad message.
+var ErrUnknownContentType = errors.New("codec: no codec for content type")
+
+// Lookup returns the codec registered for contentType.
+func Lookup(contentType string) (Codec, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := codecs[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownContentType, contentType)
+	}
+	return c, nil
+}
+
+const ContentTypeJSON = "application/json"
+
+// JSON encodes with encoding/json.
+type JSON struct{}
+
+func (JSON) ContentType() string { return ContentTypeJSON }
+
+func (JSON) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (JSON) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 21.55
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/codec
+ */
+
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type deviceEvent struct {
+	ID   string   `json:"id"`
+	JID  string   `json:"jid,omitempty"`
+	Tags []string `json:"tags"`
+}
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	want := deviceEvent{ID: "1", JID: "a", Tags: []string{"x"}}
+	for _, c := range []Codec{JSON{}, Msgpack{}} {
+		data, err := c.Marshal(want)
+		if err != nil {
+			t.Fatalf("%s: failed to marshal: %v", c.ContentType(), err)
+		}
+		var got deviceEvent
+		if err := c.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: failed to unmarshal: %v", c.ContentType(), err)
+		}
+		if got.ID != want.ID || got.JID != want.JID || len(got.Tags) != 1 {
+			t.Errorf("%s: expected %+v, got %+v", c.ContentType(), want, got)
+		}
+	}
+}
+
+func TestMsgpack_UsesJSONNames(t *testing.T) {
+	data, err := Msgpack{}.Marshal(deviceEvent{ID: "1"})
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := (Msgpack{}).Unmarshal(data, &got); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if got["id"] != "1" {
+		t.Errorf("Expected field id, got %v", got)
+	}
+	if _, ok := got["jid"]; ok {
+		t.Errorf("Expected omitempty to drop jid, got %v", got)
+	}
+}
+
+func TestProtobuf_RoundTrip(t *testing.T) {
+	data, err := Protobuf{}.Marshal(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	var got *wrapperspb.StringValue
+	if err := (Protobuf{}).Unmarshal(data, &got); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if got.GetValue() != "hello" {
+		t.Errorf("Expected hello, got %q", got.GetValue())
+	}
+
+	if _, err := (Protobuf{}).Marshal(deviceEvent{}); err == nil {
+		t.Error("Expected error for a non-protobuf value")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, ct := range []string{ContentTypeJSON, ContentTypeMsgpack, ContentTypeProtobuf} {
+		c, err := Lookup(ct)
+		if err != nil || c.ContentType() != ct {
+			t.Errorf("Expected codec for %s, got %v (%v)", ct, c, err)
+		}
+	}
+	if _, err := Lookup("text/csv"); !errors.Is(err, ErrUnknownContentType) {
+		t.Error("Expected error for an unregistered content type")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	large := []byte(strings.Repeat("pakaiwa ", 512))
+	for _, encoding := range []string{EncodingGzip, EncodingZstd} {
+		c := Compression{Encoding: encoding}
+
+		data, got, err := c.Compress(large)
+		if err != nil {
+			t.Fatalf("%s: failed to compress: %v", encoding, err)
+		}
+		if got != encoding || len(data) >= len(large) {
+			t.Errorf("%s: expected compressed value, got encoding %q and %d bytes", encoding, got, len(data))
+		}
+		plain, err := Decompress(data, got, 0)
+		if err != nil || !bytes.Equal(plain, large) {
+			t.Errorf("%s: expected the original value back (%v)", encoding, err)
+		}
+		if _, err := Decompress(data, got, len(large)-1); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s: expected ErrTooLarge above the limit, got %v", encoding, err)
+		}
+
+		small := []byte("{}")
+		if data, got, _ := c.Compress(small); got != "" || !bytes.Equal(data, small) {
+			t.Errorf("%s: expected small values uncompressed, got encoding %q", encoding, got)
+		}
+	}
+
+	if _, _, err := (Compression{Encoding: EncodingGzip, MaxSize: len(large) - 1}).Compress(large); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge above MaxSize, got %v", err)
+	}
+	if _, _, err := (Compression{Encoding: "br", MinSize: 1}).Compress(large); err == nil {
+		t.Error("Expected error for an unsupported encoding")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 21.40
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/codec
+ */
+
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings, sent in the content_encoding header of compressed messages.
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// Compression compresses message values of at least MinSize bytes.
+type Compression struct {
+	Encoding string // EncodingGzip or EncodingZstd
+	MinSize  int    // default 1024
+	// MaxSize is the largest value compressed, default DefaultMaxDecompressedSize.
+	// Keep it at or below the MaxDecompressedSize of the consumers.
+	MaxSize int
+}
+
+// Compress compresses data when it is large enough and returns the content
+// encoding it used, empty when data is returned as is. Data above MaxSize fails
+// with ErrTooLarge, as consumers would refuse to decompress it.
+func (c Compression) Compress(data []byte) ([]byte, string, error) {
+	minSize := c.MinSize
+	if minSize <= 0 {
+		minSize = 1024
+	}
+	if len(data) < minSize {
+		return data, "", nil
+	}
+	maxSize := c.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+	if len(data) > maxSize {
+		return nil, "", fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, len(data), maxSize)
+	}
+
+	var buf bytes.Buffer
+	switch c.Encoding {
+	case EncodingGzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, "", err
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+	case EncodingZstd:
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, "", err
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+	default:
+		return nil, "", fmt.Errorf("codec: unsupported content encoding %q", c.Encoding)
+	}
+	return buf.Bytes(), c.Encoding, nil
+}
+
+// DefaultMaxDecompressedSize bounds Decompress when no limit is given. The
+// broker's message.max.bytes limits the compressed value, and text such as JSON
+// compresses ten times or more, so the bound sits well above it while still
+// refusing decompression bombs.
+const DefaultMaxDecompressedSize = 64 << 20
+
+// ErrTooLarge reports a value that decompresses to more than the allowed size.
+var ErrTooLarge = errors.New("codec: decompressed value too large")
+
+// Decompress reverses Compress for the content encoding of a message. An empty
+// encoding returns data as is. Output above maxSize bytes, default
+// DefaultMaxDecompressedSize, fails with ErrTooLarge rather than being read
+// into memory.
+func Decompress(data []byte, encoding string, maxSize int) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case "":
+		return data, nil
+	case EncodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close() //nolint:errcheck
+		r = gr
+	case EncodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("codec: unsupported content encoding %q", encoding)
+	}
+
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+	out, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxSize)
+	}
+	return out, nil
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 21.20
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/codec
+ */
+
+package codec
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const ContentTypeMsgpack = "application/x-msgpack"
+
+// Msgpack encodes with MessagePack. Struct fields are named by their json tags,
+// so events need no extra tags to switch from JSON.
+type Msgpack struct{}
+
+func (Msgpack) ContentType() string { return ContentTypeMsgpack }
+
+func (Msgpack) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (Msgpack) Unmarshal(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 21.30
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/codec
+ */
+
+package codec
+
+import (
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const ContentTypeProtobuf = "application/x-protobuf"
+
+// Protobuf encodes generated protobuf messages. Events are pointers to generated
+// types, so decoding into a nil event allocates it.
+type Protobuf struct{}
+
+func (Protobuf) ContentType() string { return ContentTypeProtobuf }
+
+func (Protobuf) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("codec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (Protobuf) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Pointer {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+		}
+		if m, ok := rv.Elem().Interface().(proto.Message); ok {
+			return proto.Unmarshal(data, m)
+		}
+	}
+	return fmt.Errorf("codec: %T is not a proto.Message", v)
+}
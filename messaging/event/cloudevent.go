@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 20.10
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/event
+ */
+
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// ContentTypeCloudEvents is the content type of a structured mode message.
+	ContentTypeCloudEvents = "application/cloudevents+json"
+)
+
+// ContentMode selects how a CloudEvent is carried by a message.
+type ContentMode int
+
+const (
+	// BinaryMode sends the event data as the message body and the other
+	// attributes as headers: ce_ on Kafka, ce- on HTTP.
+	BinaryMode ContentMode = iota
+	// StructuredMode sends the whole envelope as a JSON message body.
+	StructuredMode
+)
+
+// CloudEventConfig enables CloudEvents on a producer.
+type CloudEventConfig struct {
+	Source string // URI-reference of the producing service, e.g. "/pakaiwa/api"
+	Mode   ContentMode
+}
+
+// SubjectEvent is implemented by events that set the CloudEvents subject.
+type SubjectEvent interface {
+	EventSubject() string
+}
+
+// TimedEvent is implemented by events that know when they occurred. Other events
+// get the time they are wrapped at.
+type TimedEvent interface {
+	EventTime() time.Time
+}
+
+// CloudEvent is a CloudEvents 1.0 envelope with JSON data.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time,omitzero"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// NewCloudEvent wraps evt: the id is its EventID, the type its EventName and the
+// data its JSON encoding.
+func NewCloudEvent(source string, evt Event) (CloudEvent, error) {
+	if source == "" {
+		return CloudEvent{}, errors.New("event: cloudevent source is required")
+	}
+
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return CloudEvent{}, err
+	}
+
+	ce := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              evt.EventID(),
+		Source:          source,
+		Type:            evt.EventName(),
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	if s, ok := evt.(SubjectEvent); ok {
+		ce.Subject = s.EventSubject()
+	}
+	if t, ok := evt.(TimedEvent); ok {
+		ce.Time = t.EventTime().UTC()
+	}
+	return ce, nil
+}
+
+// Attributes returns the context attributes of ce by name, without data, as
+// sent in binary mode.
+func (ce CloudEvent) Attributes() map[string]string {
+	attrs := map[string]string{
+		"specversion": ce.SpecVersion,
+		"id":          ce.ID,
+		"source":      ce.Source,
+		"type":        ce.Type,
+	}
+	if ce.Subject != "" {
+		attrs["subject"] = ce.Subject
+	}
+	if !ce.Time.IsZero() {
+		attrs["time"] = ce.Time.Format(time.RFC3339Nano)
+	}
+	return attrs
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
@@ -42,16 +44,28 @@ func NewHttpProducer(url string, log *logrus.Logger) producer.MessageProducer {
 	}
 }
 
-func (h *HttpProducer) Send(ctx context.Context, topic string, key []byte, clientJID []byte, value []byte) error {
-	req, err := http.NewRequestWithContext(ctx, "POST", h.url, bytes.NewBuffer(value))
+// Send posts msg.Value to the producer's URL. Message fields are sent as
+// X-PakaiWA-* request headers; the content_type and device_id headers map to
+// Content-Type and X-Device-Id, content_encoding to Content-Encoding, trace_id
+// to X-Trace-Id, and CloudEvents ce_ headers to ce- headers.
+func (h *HttpProducer) Send(ctx context.Context, msg producer.Message) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", h.url, bytes.NewBuffer(msg.Value))
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-PakaiWA-Topic", topic)
-	req.Header.Set("X-PakaiWA-Key", string(key))
-	req.Header.Set("X-Device-Id", string(clientJID))
+	req.Header.Set("Content-Type", producer.ContentTypeJSON)
+	req.Header.Set("X-PakaiWA-Topic", msg.Topic)
+	req.Header.Set("X-PakaiWA-Key", string(msg.Key))
+	if !msg.Timestamp.IsZero() {
+		req.Header.Set("X-PakaiWA-Timestamp", msg.Timestamp.UTC().Format(time.RFC3339Nano))
+	}
+	if msg.Partition != nil {
+		req.Header.Set("X-PakaiWA-Partition", strconv.Itoa(int(*msg.Partition)))
+	}
+	for k, v := range producer.Headers(ctx, msg) {
+		req.Header.Set(headerName(k), v)
+	}
 
 	resp, err := h.client.Do(req)
 	if err != nil {
@@ -71,6 +85,24 @@ func (h *HttpProducer) Send(ctx context.Context, topic string, key []byte, clien
 	return nil
 }
 
+func headerName(key string) string {
+	if attr, ok := strings.CutPrefix(key, producer.CloudEventsHeaderPrefix); ok {
+		return "ce-" + attr
+	}
+
+	switch key {
+	case producer.HeaderContentType, producer.HeaderCloudEventsContent:
+		return "Content-Type"
+	case producer.HeaderContentEncoding:
+		return "Content-Encoding"
+	case producer.HeaderDeviceID:
+		return "X-Device-Id"
+	case producer.HeaderTraceID:
+		return "X-Trace-Id"
+	}
+	return "X-PakaiWA-" + strings.ReplaceAll(key, "_", "-")
+}
+
 func (h *HttpProducer) Flush(_ int) int {
 	// HTTP is synchronous in this implementation, nothing to flush
 	return 0
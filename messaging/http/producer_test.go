@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 19.05
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/http
+ */
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/observability/logging/ctxmeta"
+	"github.com/sirupsen/logrus"
+)
+
+func TestHttpProducer_Send(t *testing.T) {
+	var got http.Header
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	p := NewHttpProducer(srv.URL, logrus.New())
+	ctx := ctxmeta.WithTraceID(context.Background(), "trace-1")
+	err := p.Send(ctx, producer.Message{
+		Topic: "devices",
+		Key:   []byte("a"),
+		Value: []byte(`{"id":"1"}`),
+		Headers: map[string]string{
+			producer.HeaderEventName:   "device.updated",
+			producer.HeaderContentType: "application/x-msgpack",
+			producer.HeaderDeviceID:    "jid",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"X-Pakaiwa-Topic":      "devices",
+		"X-Pakaiwa-Key":        "a",
+		"X-Pakaiwa-Event-Name": "device.updated",
+		"Content-Type":         "application/x-msgpack",
+		"X-Device-Id":          "jid",
+		"X-Trace-Id":           "trace-1",
+	}
+	for k, v := range want {
+		if got.Get(k) != v {
+			t.Errorf("Expected header %s %q, got %q", k, v, got.Get(k))
+		}
+	}
+	if string(body) != `{"id":"1"}` {
+		t.Errorf("Expected message value as body, got %s", body)
+	}
+}
+
+func TestHttpProducer_SendCloudEvent(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	p := NewHttpProducer(srv.URL, logrus.New())
+	err := p.Send(context.Background(), producer.Message{
+		Topic: "devices",
+		Value: []byte(`{"id":"1"}`),
+		Headers: map[string]string{
+			"ce_specversion":                  "1.0",
+			"ce_id":                           "1",
+			"ce_type":                         "device.updated",
+			producer.HeaderCloudEventsContent: "application/json",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"Ce-Specversion": "1.0",
+		"Ce-Id":          "1",
+		"Ce-Type":        "device.updated",
+		"Content-Type":   "application/json",
+	}
+	for k, v := range want {
+		if got.Get(k) != v {
+			t.Errorf("Expected header %s %q, got %q", k, v, got.Get(k))
+		}
+	}
+}
@@ -16,15 +16,88 @@
 package kafka
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/PakaiWA/pakaiwa-platform/messaging/codec"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/schema"
+	"github.com/PakaiWA/pakaiwa-platform/observability/metrics"
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+// CommitStrategy controls when a Consumer commits the offsets of handled messages.
+type CommitStrategy int
+
+const (
+	// CommitSync commits after every handled message and waits for the broker.
+	CommitSync CommitStrategy = iota
+	// CommitAsync commits in the background once CommitBatchSize messages are handled.
+	CommitAsync
+	// CommitPeriodic commits every CommitInterval.
+	CommitPeriodic
 )
 
 type ConsumerConfig struct {
 	Brokers []string
 	GroupID string
 	Options map[string]any
+
+	// The fields below are used by Consumer only.
+	Topics          []string
+	Commit          CommitStrategy
+	CommitBatchSize int           // CommitAsync, default 100
+	CommitInterval  time.Duration // CommitPeriodic, default 5s
+	PollTimeout     time.Duration // default 100ms
+	// Backoff delays the redelivery of a message whose handler failed.
+	Backoff backoff.Policy
+	// Retry, when set, moves failed messages to retry topics and then a DLQ instead
+	// of redelivering them in place.
+	Retry *RetryPolicy
+	// Concurrency above 1 hands messages to that many workers by message key:
+	// messages with the same key are handled in order, different keys in parallel.
+	Concurrency int
+	// MaxInFlight pauses a partition once this many of its messages are unfinished
+	// in concurrent mode, default 1000.
+	MaxInFlight int
+	// OnAssigned is called after partitions are assigned to the consumer, and
+	// OnRevoked before they are taken away, once their handled offsets are
+	// committed. Both run on the polling goroutine. Set
+	// "partition.assignment.strategy" to "cooperative-sticky" in Options to move
+	// only the partitions that change owner on a rebalance.
+	OnAssigned func(partitions []kafka.TopicPartition)
+	OnRevoked  func(partitions []kafka.TopicPartition)
+	// RevokeTimeout bounds the wait for in-flight handlers of revoked partitions
+	// in concurrent mode, default 30s.
+	RevokeTimeout time.Duration
+	// Codec decodes messages without a content type, default codec.JSON. Other
+	// messages are decoded by the codec registered for their content type and
+	// decompressed according to their content_encoding header. CloudEvents are
+	// accepted in both content modes.
+	Codec codec.Codec
+	// MaxDecompressedSize bounds the decompressed size of a message value,
+	// default codec.DefaultMaxDecompressedSize. Larger messages fail to decode.
+	MaxDecompressedSize int
+	// Transactions, when set, handles every message in a transaction of this
+	// producer that also commits its offset: events the handler sends through the
+	// producer are written exactly once with the offset, and the consumer reads
+	// with isolation.level read_committed. Concurrency and Retry are not supported.
+	Transactions *KafkaProducer
+	// StatsInterval exports librdkafka statistics, including the lag of every
+	// assigned partition, through observability/metrics at this interval,
+	// default 30s. A negative interval disables them.
+	StatsInterval time.Duration
 }
 
 func NewKafkaConsumer(cfg ConsumerConfig) (*kafka.Consumer, error) {
@@ -39,3 +112,540 @@ func NewKafkaConsumer(cfg ConsumerConfig) (*kafka.Consumer, error) {
 
 	return kafka.NewConsumer(m)
 }
+
+// Metadata describes the Kafka message an event was decoded from.
+type Metadata struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Headers   map[string]string
+	Timestamp time.Time
+	Attempt   int // 1 on first delivery, incremented on each redelivery after a failure
+}
+
+type pausedPartition struct {
+	tp    kafka.TopicPartition
+	until time.Time
+}
+
+func metadataOf(msg *kafka.Message) Metadata {
+	md := Metadata{
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Key:       msg.Key,
+		Headers:   make(map[string]string, len(msg.Headers)),
+		Timestamp: msg.Timestamp,
+		Attempt:   1,
+	}
+	if msg.TopicPartition.Topic != nil {
+		md.Topic = *msg.TopicPartition.Topic
+	}
+	for _, h := range msg.Headers {
+		md.Headers[h.Key] = string(h.Value)
+	}
+	return md
+}
+
+// Handler processes events decoded by a Consumer. Returning an error redelivers
+// the message after a backoff; the partition does not advance until it succeeds.
+type Handler[T event.Event] interface {
+	Handle(ctx context.Context, evt T, md Metadata) error
+}
+
+// HandlerFunc adapts a function to a Handler.
+type HandlerFunc[T event.Event] func(ctx context.Context, evt T, md Metadata) error
+
+func (f HandlerFunc[T]) Handle(ctx context.Context, evt T, md Metadata) error {
+	return f(ctx, evt, md)
+}
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// Consumer subscribes to topics, decodes each message into T and passes it
+// to a Handler. Offsets are committed only for handled messages, so delivery is at
+// least once.
+type Consumer[T event.Event] struct {
+	c       *kafka.Consumer
+	cfg     ConsumerConfig
+	handler Handler[T]
+	log     *logrus.Logger
+
+	retry      *retrier
+	workers    *workerPool[T]
+	failures   map[partitionKey]int
+	paused     map[partitionKey]pausedPartition
+	pending    int
+	lastCommit time.Time
+	committing atomic.Bool
+	commitWG   sync.WaitGroup
+	fatal      error
+}
+
+// NewConsumer creates a Consumer for cfg. Automatic commits and offset storage are
+// disabled regardless of cfg.Options; the Consumer manages both.
+func NewConsumer[T event.Event](cfg ConsumerConfig, handler Handler[T], log *logrus.Logger) (*Consumer[T], error) {
+	if len(cfg.Topics) == 0 {
+		return nil, errors.New("kafka: consumer needs at least one topic")
+	}
+	if cfg.CommitBatchSize <= 0 {
+		cfg.CommitBatchSize = 100
+	}
+	if cfg.CommitInterval <= 0 {
+		cfg.CommitInterval = 5 * time.Second
+	}
+	if cfg.PollTimeout <= 0 {
+		cfg.PollTimeout = 100 * time.Millisecond
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = 1000
+	}
+	if cfg.RevokeTimeout <= 0 {
+		cfg.RevokeTimeout = 30 * time.Second
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = codec.JSON{}
+	}
+	if cfg.StatsInterval == 0 {
+		cfg.StatsInterval = 30 * time.Second
+	}
+	if cfg.Transactions != nil && (cfg.Concurrency > 1 || cfg.Retry != nil) {
+		return nil, errors.New("kafka: transactions do not support Concurrency or Retry")
+	}
+
+	options := make(map[string]any, len(cfg.Options)+2)
+	for k, v := range cfg.Options {
+		options[k] = v
+	}
+	options["enable.auto.commit"] = false
+	options["enable.auto.offset.store"] = false
+	if cfg.StatsInterval > 0 {
+		options["statistics.interval.ms"] = int(cfg.StatsInterval.Milliseconds())
+	}
+	if cfg.Transactions != nil {
+		options["isolation.level"] = "read_committed"
+	}
+
+	raw := cfg
+	raw.Options = options
+	c, err := NewKafkaConsumer(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := &Consumer[T]{
+		c:          c,
+		cfg:        cfg,
+		handler:    handler,
+		log:        log,
+		failures:   make(map[partitionKey]int),
+		paused:     make(map[partitionKey]pausedPartition),
+		lastCommit: time.Now(),
+	}
+
+	if cfg.Retry != nil {
+		if consumer.retry, err = newRetrier(cfg); err != nil {
+			_ = c.Close() //nolint:errcheck
+			return nil, err
+		}
+	}
+	if cfg.Concurrency > 1 {
+		consumer.workers = newWorkerPool[T](cfg.Concurrency, cfg.MaxInFlight)
+	}
+
+	return consumer, nil
+}
+
+// Run consumes until ctx is canceled, then commits the offsets of every handled
+// message and closes the consumer. It returns early only on a fatal Kafka error
+// or a message whose content type has no registered codec.
+func (c *Consumer[T]) Run(ctx context.Context) error {
+	topics := c.cfg.Topics
+	if c.retry != nil {
+		topics = append(slices.Clone(topics), c.retry.topics()...)
+	}
+
+	if err := c.c.SubscribeTopics(topics, c.rebalance); err != nil {
+		_ = c.c.Close() //nolint:errcheck
+		if c.retry != nil {
+			c.retry.close()
+		}
+		return err
+	}
+	defer c.close()
+
+	c.log.WithFields(logrus.Fields{
+		"topics":      topics,
+		"group":       c.cfg.GroupID,
+		"concurrency": max(c.cfg.Concurrency, 1),
+		"module":      "Kafka",
+	}).Info("Kafka consumer started")
+
+	if c.workers != nil {
+		c.workers.start(ctx, c.work)
+		defer c.stopWorkers()
+	}
+
+	timeoutMs := int(c.cfg.PollTimeout.Milliseconds())
+	for ctx.Err() == nil {
+		switch e := c.c.Poll(timeoutMs).(type) {
+		case *kafka.Message:
+			if c.workers != nil {
+				c.dispatch(e)
+			} else {
+				c.process(ctx, e)
+			}
+		case *kafka.Stats:
+			if err := metrics.ObserveKafkaStats(c.cfg.GroupID, e.String()); err != nil {
+				c.log.WithError(err).WithField("module", "Kafka").Warn("failed to parse kafka statistics")
+			}
+		case kafka.Error:
+			if e.IsFatal() {
+				c.log.WithError(e).Error("Kafka consumer fatal error")
+				return e
+			}
+			c.log.WithError(e).Warn("Kafka consumer error")
+		}
+
+		if c.fatal != nil {
+			c.log.WithError(c.fatal).Error("Kafka consumer fatal error")
+			return c.fatal
+		}
+		if c.workers != nil {
+			c.drainResults()
+		}
+		c.resumeDue()
+
+		if c.cfg.Commit == CommitPeriodic && time.Since(c.lastCommit) >= c.cfg.CommitInterval {
+			c.commit()
+		}
+	}
+
+	c.log.WithField("module", "Kafka").Info("Kafka consumer stopping")
+	return nil
+}
+
+// prepare decodes msg. It returns false when msg must not be handled now: a retry
+// message that is not yet due, one whose schema registry is unavailable, which is
+// redelivered with backoff, or one that can never be decoded. Undecodable
+// messages are dead-lettered when a retry policy is set, skipped otherwise, and
+// reported as finished through done; when the dead-letter copy cannot be written
+// the message is redelivered instead. A content type without a registered codec
+// stops the consumer without committing the message.
+func (c *Consumer[T]) prepare(msg *kafka.Message, done func(*kafka.Message)) (T, Metadata, bool) {
+	var evt T
+	md := metadataOf(msg)
+	md.Attempt = attemptsOf(md) + c.failures[partitionKey{md.Topic, md.Partition}] + 1
+
+	if c.retry != nil {
+		if due := c.retry.due(md); time.Now().Before(due) {
+			c.pause(msg, due)
+			return evt, md, false
+		}
+	}
+
+	if err := c.decode(msg.Value, md, &evt); err != nil {
+		if errors.Is(err, schema.ErrUnavailable) {
+			c.redeliver(msg, md, err)
+			return evt, md, false
+		}
+		if errors.Is(err, codec.ErrUnknownContentType) {
+			// A codec missing from this deployment; stop rather than skip every
+			// message of that type.
+			c.fatal = err
+			return evt, md, false
+		}
+
+		entry := c.entry(md)
+		if c.retry != nil {
+			if _, ferr := c.retry.forward(msg, md, err, true); ferr != nil {
+				entry.WithError(ferr).Error("failed to dead-letter kafka message")
+				c.redeliver(msg, md, err)
+				return evt, md, false
+			}
+		}
+		entry.WithError(err).Error("failed to decode kafka message, skipping")
+		metrics.KafkaMessagesFailed.WithLabelValues(c.cfg.GroupID, md.Topic).Inc()
+		done(msg)
+		return evt, md, false
+	}
+
+	return evt, md, true
+}
+
+// decode decompresses value and unmarshals it with the codec of its content type.
+// A structured mode CloudEvent is unwrapped and its data decoded by the
+// envelope's datacontenttype.
+func (c *Consumer[T]) decode(value []byte, md Metadata, evt *T) error {
+	value, err := codec.Decompress(value, md.Headers[producer.HeaderContentEncoding], c.cfg.MaxDecompressedSize)
+	if err != nil {
+		return err
+	}
+
+	ct, err := mediaType(contentType(md.Headers))
+	if err != nil {
+		return err
+	}
+	if ct == event.ContentTypeCloudEvents {
+		var ce event.CloudEvent
+		if err := json.Unmarshal(value, &ce); err != nil {
+			return fmt.Errorf("kafka: invalid cloudevent envelope: %w", err)
+		}
+		value = ce.Data
+		if ct, err = mediaType(ce.DataContentType); err != nil {
+			return err
+		}
+	}
+
+	dec := c.cfg.Codec
+	if ct != "" {
+		if dec, err = codec.Lookup(ct); err != nil {
+			return err
+		}
+	}
+	return dec.Unmarshal(value, evt)
+}
+
+// mediaType strips the parameters from a content type, so that
+// "application/json; charset=utf-8" finds the JSON codec.
+func mediaType(ct string) (string, error) {
+	if ct == "" {
+		return "", nil
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return "", fmt.Errorf("kafka: invalid content type %q: %w", ct, err)
+	}
+	return mt, nil
+}
+
+// contentType returns the content_type header of a message or, for messages from
+// CloudEvents producers without it, the content-type header or the
+// ce_datacontenttype attribute.
+func contentType(headers map[string]string) string {
+	for _, k := range []string{
+		producer.HeaderContentType,
+		producer.HeaderCloudEventsContent,
+		producer.CloudEventsHeaderPrefix + "datacontenttype",
+	} {
+		if ct, ok := headers[k]; ok {
+			return ct
+		}
+	}
+	return ""
+}
+
+func (c *Consumer[T]) entry(md Metadata) *logrus.Entry {
+	return c.log.WithFields(logrus.Fields{
+		"topic":     md.Topic,
+		"partition": md.Partition,
+		"offset":    md.Offset,
+		"module":    "Kafka",
+	})
+}
+
+// forward moves a failed message to its retry topic and reports whether it did.
+func (c *Consumer[T]) forward(msg *kafka.Message, md Metadata, err error) bool {
+	if c.retry == nil {
+		return false
+	}
+
+	target, ferr := c.retry.forward(msg, md, err, false)
+	if ferr != nil {
+		c.entry(md).WithError(ferr).Error("failed to forward kafka message, redelivering")
+		return false
+	}
+
+	c.entry(md).WithError(err).WithFields(logrus.Fields{
+		"attempt": md.Attempt,
+		"target":  target,
+	}).Warn("kafka handler failed, message forwarded")
+	return true
+}
+
+// process handles msg on the polling goroutine. A failed message is redelivered
+// by rewinding its partition, which holds back the rest of the partition.
+func (c *Consumer[T]) process(ctx context.Context, msg *kafka.Message) {
+	evt, md, ok := c.prepare(msg, func(m *kafka.Message) { c.done(ctx, m) })
+	if !ok {
+		return
+	}
+	key := partitionKey{md.Topic, md.Partition}
+
+	var err error
+	if c.cfg.Transactions != nil {
+		err = c.transact(ctx, msg, func(ctx context.Context) error { return c.handle(ctx, evt, md) })
+		var kerr kafka.Error
+		if errors.As(err, &kerr) && kerr.IsFatal() {
+			c.fatal = err
+			return
+		}
+	} else {
+		err = c.handle(ctx, evt, md)
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		if c.forward(msg, md, err) {
+			delete(c.failures, key)
+			c.done(ctx, msg)
+			return
+		}
+
+		c.failures[key] = md.Attempt - attemptsOf(md)
+		delay := c.cfg.Backoff.Delay(md.Attempt)
+		c.entry(md).WithError(err).WithFields(logrus.Fields{
+			"attempt":  md.Attempt,
+			"retry_in": delay,
+		}).Warn("kafka handler failed, redelivering")
+
+		// Rewind so the message is fetched again; messages already fetched for the
+		// partition are discarded by the seek.
+		if err := c.c.Seek(msg.TopicPartition, 0); err != nil {
+			c.entry(md).WithError(err).Error("failed to seek kafka partition")
+		}
+		_ = backoff.Sleep(ctx, delay) //nolint:errcheck
+		return
+	}
+
+	delete(c.failures, key)
+	if c.cfg.Transactions == nil {
+		c.done(ctx, msg)
+	}
+}
+
+// redeliver rewinds msg's partition and pauses it for the backoff of its attempt,
+// so msg is handled again later and its offset is not committed meanwhile.
+func (c *Consumer[T]) redeliver(msg *kafka.Message, md Metadata, err error) {
+	c.failures[partitionKey{md.Topic, md.Partition}] = md.Attempt - attemptsOf(md)
+	delay := c.cfg.Backoff.Delay(md.Attempt)
+	c.entry(md).WithError(err).WithFields(logrus.Fields{
+		"attempt":  md.Attempt,
+		"retry_in": delay,
+	}).Warn("failed to prepare kafka message, redelivering")
+	c.pause(msg, time.Now().Add(delay))
+}
+
+// pause stops fetching msg's partition until due and rewinds it to msg.
+func (c *Consumer[T]) pause(msg *kafka.Message, due time.Time) {
+	tp := kafka.TopicPartition{Topic: msg.TopicPartition.Topic, Partition: msg.TopicPartition.Partition}
+	if err := c.c.Pause([]kafka.TopicPartition{tp}); err != nil {
+		c.log.WithError(err).WithField("module", "Kafka").Error("failed to pause kafka partition")
+	}
+	if err := c.c.Seek(msg.TopicPartition, 0); err != nil {
+		c.log.WithError(err).WithField("module", "Kafka").Error("failed to seek kafka partition")
+	}
+	c.paused[partitionKey{*tp.Topic, tp.Partition}] = pausedPartition{tp: tp, until: due}
+}
+
+func (c *Consumer[T]) resumeDue() {
+	now := time.Now()
+	for key, p := range c.paused {
+		if now.Before(p.until) {
+			continue
+		}
+		if t := c.trackerOf(key); t != nil && t.paused {
+			// Still paused for backpressure; complete resumes it.
+			delete(c.paused, key)
+			continue
+		}
+		if err := c.c.Resume([]kafka.TopicPartition{p.tp}); err != nil {
+			c.log.WithError(err).WithField("module", "Kafka").Error("failed to resume kafka partition")
+			continue
+		}
+		delete(c.paused, key)
+	}
+}
+
+func (c *Consumer[T]) handle(ctx context.Context, evt T, md Metadata) (err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("kafka handler panicked: %v", r)
+		}
+
+		metrics.KafkaHandlerDuration.WithLabelValues(c.cfg.GroupID, md.Topic).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.KafkaMessagesFailed.WithLabelValues(c.cfg.GroupID, md.Topic).Inc()
+		} else {
+			metrics.KafkaMessagesProcessed.WithLabelValues(c.cfg.GroupID, md.Topic).Inc()
+		}
+	}()
+
+	return c.handler.Handle(ctx, evt, md)
+}
+
+// done stores the offset of a finished message and commits according to the
+// strategy. With transactions the offset is committed in a transaction instead,
+// so it never falls behind offsets committed by earlier transactions; when ctx has
+// ended that transaction fails and the message is consumed again.
+func (c *Consumer[T]) done(ctx context.Context, msg *kafka.Message) {
+	if c.cfg.Transactions != nil {
+		if err := c.transact(ctx, msg, nil); err != nil {
+			c.log.WithError(err).WithField("module", "Kafka").Error("failed to commit kafka offset in transaction")
+		}
+		return
+	}
+
+	tp := msg.TopicPartition
+	tp.Offset++
+	c.store(tp)
+}
+
+// store records tp.Offset as the next offset to consume and commits according to
+// the strategy.
+func (c *Consumer[T]) store(tp kafka.TopicPartition) {
+	if _, err := c.c.StoreOffsets([]kafka.TopicPartition{tp}); err != nil {
+		c.log.WithError(err).WithField("module", "Kafka").Error("failed to store kafka offset")
+		return
+	}
+	c.pending++
+
+	switch c.cfg.Commit {
+	case CommitSync:
+		c.commit()
+	case CommitAsync:
+		if c.pending >= c.cfg.CommitBatchSize && c.committing.CompareAndSwap(false, true) {
+			c.pending = 0
+			c.commitWG.Add(1)
+			go func() {
+				defer func() {
+					c.committing.Store(false)
+					c.commitWG.Done()
+				}()
+				c.commitStored()
+			}()
+		}
+	}
+}
+
+func (c *Consumer[T]) commit() {
+	c.pending = 0
+	c.lastCommit = time.Now()
+	c.commitStored()
+}
+
+func (c *Consumer[T]) commitStored() {
+	_, err := c.c.Commit()
+	var kerr kafka.Error
+	if err != nil && !(errors.As(err, &kerr) && kerr.Code() == kafka.ErrNoOffset) {
+		c.log.WithError(err).WithField("module", "Kafka").Error("failed to commit kafka offsets")
+	}
+}
+
+func (c *Consumer[T]) close() {
+	c.commitWG.Wait()
+	c.commit()
+
+	if err := c.c.Close(); err != nil {
+		c.log.WithError(err).WithField("module", "Kafka").Error("failed to close kafka consumer")
+	}
+	if c.retry != nil {
+		c.retry.close()
+	}
+}
@@ -0,0 +1,508 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 23.05
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/codec"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/schema"
+	"github.com/PakaiWA/pakaiwa-platform/observability/metrics"
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+type deviceEvent struct {
+	ID  string `json:"id"`
+	JID string `json:"jid"`
+}
+
+func (e deviceEvent) EventID() string   { return e.ID }
+func (e deviceEvent) EventName() string { return "device.updated" }
+func (e deviceEvent) EventKey() string  { return e.JID }
+
+func newMockCluster(t *testing.T, topics ...string) *kafka.MockCluster {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping Kafka mock cluster test in short mode")
+	}
+
+	mc, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("Failed to create mock cluster: %v", err)
+	}
+	t.Cleanup(mc.Close)
+
+	for _, topic := range topics {
+		if err := mc.CreateTopic(topic, 1, 1); err != nil {
+			t.Fatalf("Failed to create topic: %v", err)
+		}
+	}
+	return mc
+}
+
+func produceEvents(t *testing.T, brokers, topic string, events ...deviceEvent) {
+	t.Helper()
+
+	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers, "go.delivery.reports": false})
+	if err != nil {
+		t.Fatalf("Failed to create producer: %v", err)
+	}
+	defer p.Close()
+
+	for _, evt := range events {
+		value, _ := json.Marshal(evt) //nolint:errcheck
+		err := p.Produce(&kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+			Key:            []byte(evt.JID),
+			Value:          value,
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to produce: %v", err)
+		}
+	}
+	if n := p.Flush(5000); n != 0 {
+		t.Fatalf("Expected all messages to be flushed, %d left", n)
+	}
+}
+
+func committedOffset(t *testing.T, brokers, group, topic string) kafka.Offset {
+	t.Helper()
+
+	c, err := NewKafkaConsumer(ConsumerConfig{Brokers: []string{brokers}, GroupID: group})
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer func() {
+		_ = c.Close() //nolint:errcheck
+	}()
+
+	offsets, err := c.Committed([]kafka.TopicPartition{{Topic: &topic, Partition: 0}}, 5000)
+	if err != nil {
+		t.Fatalf("Failed to read committed offsets: %v", err)
+	}
+	return offsets[0].Offset
+}
+
+func TestNewConsumer_StatsIntervalDefault(t *testing.T) {
+	handler := HandlerFunc[deviceEvent](func(context.Context, deviceEvent, Metadata) error { return nil })
+
+	c, err := NewConsumer(ConsumerConfig{Brokers: []string{"localhost:9092"}, GroupID: "test", Topics: []string{"devices"}}, handler, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer c.c.Close() //nolint:errcheck
+
+	if c.cfg.StatsInterval != 30*time.Second {
+		t.Errorf("Expected StatsInterval 30s so lag is exported, got %v", c.cfg.StatsInterval)
+	}
+}
+
+func TestConsumer_HandlesAndCommits(t *testing.T) {
+	for _, strategy := range []CommitStrategy{CommitSync, CommitAsync, CommitPeriodic} {
+		mc := newMockCluster(t, "devices")
+		brokers := mc.BootstrapServers()
+		produceEvents(t, brokers, "devices",
+			deviceEvent{ID: "1", JID: "a"},
+			deviceEvent{ID: "2", JID: "b"},
+			deviceEvent{ID: "3", JID: "a"},
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+
+		var mu sync.Mutex
+		var seen []string
+		failed := false
+		handler := HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if evt.ID == "2" && !failed {
+				failed = true
+				return errors.New("temporary failure")
+			}
+			if evt.ID == "2" && md.Attempt != 2 {
+				t.Errorf("Expected redelivery attempt 2, got %d", md.Attempt)
+			}
+			seen = append(seen, evt.ID)
+			if len(seen) == 3 {
+				cancel()
+			}
+			return nil
+		})
+
+		c, err := NewConsumer(ConsumerConfig{
+			Brokers:        []string{brokers},
+			GroupID:        "test",
+			Topics:         []string{"devices"},
+			Options:        map[string]any{"auto.offset.reset": "earliest"},
+			Commit:         strategy,
+			CommitInterval: 10 * time.Millisecond,
+			Backoff:        backoff.Policy{Initial: 10 * time.Millisecond, Max: 10 * time.Millisecond},
+		}, handler, logrus.New())
+		if err != nil {
+			t.Fatalf("Failed to create consumer: %v", err)
+		}
+
+		if err := c.Run(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cancel()
+
+		if len(seen) != 3 || seen[0] != "1" || seen[1] != "2" || seen[2] != "3" {
+			t.Errorf("Strategy %d: expected events in order 1,2,3, got %v", strategy, seen)
+		}
+		if off := committedOffset(t, brokers, "test", "devices"); off != 3 {
+			t.Errorf("Strategy %d: expected committed offset 3, got %v", strategy, off)
+		}
+	}
+}
+
+func TestConsumer_DecodesByContentType(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+	brokers := mc.BootstrapServers()
+
+	mp := NewKafkaProducer(&kafka.ConfigMap{"bootstrap.servers": brokers}, logrus.New())
+	if mp == nil {
+		t.Fatal("Failed to create producer")
+	}
+	defer mp.Close() //nolint:errcheck
+
+	senders := []*Producer[deviceEvent]{
+		{Producer: mp, Topic: "devices", Log: logrus.New()},
+		{Producer: mp, Topic: "devices", Log: logrus.New(), Codec: codec.Msgpack{}},
+		{Producer: mp, Topic: "devices", Log: logrus.New(), Codec: codec.Msgpack{}, Compression: &codec.Compression{Encoding: codec.EncodingGzip, MinSize: 1}},
+	}
+	for i, p := range senders {
+		if _, _, err := p.SendSync(context.Background(), deviceEvent{ID: strconv.Itoa(i + 1), JID: "a"}, ""); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var seen []deviceEvent
+	var encodings []string
+	handler := HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		seen = append(seen, evt)
+		encodings = append(encodings, md.Headers[producer.HeaderContentEncoding])
+		if len(seen) == len(senders) {
+			cancel()
+		}
+		return nil
+	})
+
+	c, err := NewConsumer(ConsumerConfig{
+		Brokers: []string{brokers},
+		GroupID: "test",
+		Topics:  []string{"devices"},
+		Options: map[string]any{"auto.offset.reset": "earliest"},
+	}, handler, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("Expected 3 events, got %v", seen)
+	}
+	for i, evt := range seen {
+		if evt.ID != strconv.Itoa(i+1) || evt.JID != "a" {
+			t.Errorf("Expected event %d from a, got %+v", i+1, evt)
+		}
+	}
+	if encodings[2] != codec.EncodingGzip {
+		t.Errorf("Expected the last event gzip compressed, got %v", encodings)
+	}
+}
+
+func TestConsumer_DecodeParsesMediaType(t *testing.T) {
+	c := &Consumer[deviceEvent]{cfg: ConsumerConfig{Codec: codec.JSON{}, MaxDecompressedSize: 64}}
+
+	var evt deviceEvent
+	md := Metadata{Headers: map[string]string{producer.HeaderContentType: "Application/JSON; charset=utf-8"}}
+	if err := c.decode([]byte(`{"id":"1","jid":"a"}`), md, &evt); err != nil || evt.ID != "1" {
+		t.Errorf("Expected the JSON codec for a content type with parameters, got %+v (%v)", evt, err)
+	}
+
+	md.Headers[producer.HeaderContentType] = "application/json; charset"
+	if err := c.decode([]byte(`{}`), md, &evt); err == nil {
+		t.Error("Expected an error for a malformed content type")
+	}
+
+	large, encoding, err := codec.Compression{Encoding: codec.EncodingGzip, MinSize: 1}.Compress([]byte(`{"id":"` + strings.Repeat("x", 100) + `"}`))
+	if err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	md = Metadata{Headers: map[string]string{producer.HeaderContentEncoding: encoding}}
+	if err := c.decode(large, md, &evt); !errors.Is(err, codec.ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge above MaxDecompressedSize, got %v", err)
+	}
+}
+
+func TestConsumer_DecodesCloudEvents(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+	brokers := mc.BootstrapServers()
+
+	mp := NewKafkaProducer(&kafka.ConfigMap{"bootstrap.servers": brokers}, logrus.New())
+	if mp == nil {
+		t.Fatal("Failed to create producer")
+	}
+	defer mp.Close() //nolint:errcheck
+
+	modes := []event.ContentMode{event.BinaryMode, event.StructuredMode}
+	for i, mode := range modes {
+		p := &Producer[deviceEvent]{
+			Producer:    mp,
+			Topic:       "devices",
+			Log:         logrus.New(),
+			CloudEvents: &event.CloudEventConfig{Source: "/pakaiwa/test", Mode: mode},
+		}
+		if _, _, err := p.SendSync(context.Background(), deviceEvent{ID: strconv.Itoa(i + 1), JID: "a"}, ""); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+	}
+
+	// A binary mode message from a producer that only sets the CloudEvents
+	// headers is decoded by its content-type.
+	msg, err := producer.CloudEventMessage("devices", deviceEvent{ID: "3", JID: "a"}, event.CloudEventConfig{Source: "/other"})
+	if err != nil {
+		t.Fatalf("Failed to build message: %v", err)
+	}
+	delete(msg.Headers, producer.HeaderContentType)
+	if _, _, err := mp.(producer.SyncSender).SendSync(context.Background(), msg); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var seen []deviceEvent
+	var names []string
+	handler := HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		seen = append(seen, evt)
+		names = append(names, md.Headers[producer.HeaderEventName])
+		if len(seen) == 3 {
+			cancel()
+		}
+		return nil
+	})
+
+	c, err := NewConsumer(ConsumerConfig{
+		Brokers: []string{brokers},
+		GroupID: "test",
+		Topics:  []string{"devices"},
+		Options: map[string]any{"auto.offset.reset": "earliest"},
+	}, handler, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("Expected 3 events, got %v", seen)
+	}
+	for i, evt := range seen {
+		if evt.ID != strconv.Itoa(i+1) || evt.JID != "a" {
+			t.Errorf("Expected event %d from a, got %+v", i+1, evt)
+		}
+		if names[i] != "device.updated" {
+			t.Errorf("Expected event name device.updated, got %q", names[i])
+		}
+	}
+}
+
+// flakyRegistry fails the first fetches by ID, as a registry during an outage.
+type flakyRegistry struct {
+	*schema.Memory
+	failures atomic.Int32
+}
+
+func (r *flakyRegistry) SchemaByID(ctx context.Context, id int) (schema.Schema, error) {
+	if r.failures.Add(-1) >= 0 {
+		return schema.Schema{}, context.DeadlineExceeded
+	}
+	return r.Memory.SchemaByID(ctx, id)
+}
+
+func TestConsumer_RedeliversWhileRegistryUnavailable(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+	brokers := mc.BootstrapServers()
+
+	reg := &flakyRegistry{Memory: schema.NewMemory()}
+	id, err := reg.Register(context.Background(), "devices-value", schema.Schema{Schema: `{"type":"object"}`})
+	if err != nil {
+		t.Fatalf("Failed to register schema: %v", err)
+	}
+	reg.failures.Store(2)
+	ser, err := schema.NewSerializer(context.Background(), schema.SerializerConfig{Registry: reg})
+	if err != nil {
+		t.Fatalf("Failed to create serializer: %v", err)
+	}
+
+	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers, "go.delivery.reports": false})
+	if err != nil {
+		t.Fatalf("Failed to create producer: %v", err)
+	}
+	topic := "devices"
+	value := schema.Frame(id, []byte(`{"id":"1","jid":"a"}`))
+	_ = p.Produce(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0}, Value: value}, nil) //nolint:errcheck
+	p.Flush(5000)
+	p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var got []Metadata
+	c, err := NewConsumer(ConsumerConfig{
+		Brokers: []string{brokers},
+		GroupID: "test",
+		Topics:  []string{topic},
+		Codec:   ser,
+		Backoff: backoff.Policy{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond},
+		Options: map[string]any{"auto.offset.reset": "earliest"},
+	}, HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		got = append(got, md)
+		cancel()
+		return nil
+	}), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(got) != 1 || got[0].Attempt != 3 {
+		t.Fatalf("Expected the event on its third attempt, got %+v", got)
+	}
+	if off := committedOffset(t, brokers, "test", topic); off != 1 {
+		t.Errorf("Expected committed offset 1, got %v", off)
+	}
+}
+
+func TestConsumer_StopsOnUnknownContentType(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+	brokers := mc.BootstrapServers()
+
+	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers, "go.delivery.reports": false})
+	if err != nil {
+		t.Fatalf("Failed to create producer: %v", err)
+	}
+	topic := "devices"
+	_ = p.Produce(&kafka.Message{ //nolint:errcheck
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0},
+		Value:          []byte("id,jid"),
+		Headers:        []kafka.Header{{Key: producer.HeaderContentType, Value: []byte("text/csv")}},
+	}, nil)
+	p.Flush(5000)
+	p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c, err := NewConsumer(ConsumerConfig{
+		Brokers: []string{brokers},
+		GroupID: "test",
+		Topics:  []string{topic},
+		Options: map[string]any{"auto.offset.reset": "earliest"},
+	}, HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		t.Error("Expected no message to be handled")
+		return nil
+	}), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+
+	if err := c.Run(ctx); !errors.Is(err, codec.ErrUnknownContentType) {
+		t.Fatalf("Expected ErrUnknownContentType, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("Expected the consumer to stop before its context ended")
+	}
+	if off := committedOffset(t, brokers, "test", topic); off != kafka.OffsetInvalid {
+		t.Errorf("Expected no committed offset, got %v", off)
+	}
+}
+
+func TestConsumer_SkipsUndecodable(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+	brokers := mc.BootstrapServers()
+
+	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers, "go.delivery.reports": false})
+	if err != nil {
+		t.Fatalf("Failed to create producer: %v", err)
+	}
+	topic := "devices"
+	_ = p.Produce(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0}, Value: []byte("{not json")}, nil) //nolint:errcheck
+	p.Flush(5000)
+	p.Close()
+	produceEvents(t, brokers, topic, deviceEvent{ID: "1", JID: "a"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	processed := testutil.ToFloat64(metrics.KafkaMessagesProcessed.WithLabelValues("test", topic))
+	failed := testutil.ToFloat64(metrics.KafkaMessagesFailed.WithLabelValues("test", topic))
+
+	var got []string
+	c, err := NewConsumer(ConsumerConfig{
+		Brokers: []string{brokers},
+		GroupID: "test",
+		Topics:  []string{topic},
+		Options: map[string]any{"auto.offset.reset": "earliest"},
+	}, HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		got = append(got, evt.ID)
+		cancel()
+		return nil
+	}), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "1" {
+		t.Errorf("Expected only the valid event, got %v", got)
+	}
+	if off := committedOffset(t, brokers, "test", topic); off != 2 {
+		t.Errorf("Expected committed offset 2, got %v", off)
+	}
+	if d := testutil.ToFloat64(metrics.KafkaMessagesProcessed.WithLabelValues("test", topic)) - processed; d != 1 {
+		t.Errorf("Expected 1 processed message, got %v", d)
+	}
+	if d := testutil.ToFloat64(metrics.KafkaMessagesFailed.WithLabelValues("test", topic)) - failed; d != 1 {
+		t.Errorf("Expected 1 failed message, got %v", d)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 16.40
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/observability/metrics"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+type deliveryTokenKey struct{}
+
+// WithDeliveryToken returns a copy of ctx whose messages sent through
+// KafkaProducer.Send carry token to their DeliveryReport, e.g. an outbox row ID.
+func WithDeliveryToken(ctx context.Context, token any) context.Context {
+	return context.WithValue(ctx, deliveryTokenKey{}, token)
+}
+
+// DeliveryReport is the outcome of one message sent through KafkaProducer.Send.
+type DeliveryReport struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Token     any           // set with WithDeliveryToken, nil otherwise
+	Latency   time.Duration // from Send to the broker's acknowledgement
+	Err       error
+}
+
+// DeliveryHandler receives delivery reports on the poll loop goroutine, so it
+// should return quickly.
+type DeliveryHandler func(r DeliveryReport)
+
+// TokenRefresher returns a new OAUTHBEARER token. config is the value of
+// sasl.oauthbearer.config.
+type TokenRefresher func(ctx context.Context, config string) (kafka.OAuthBearerToken, error)
+
+// PollOptions configure StartProducerPollLoop.
+type PollOptions struct {
+	OnDelivery []DeliveryHandler
+	// OnTokenRefresh is required when sasl.mechanism is OAUTHBEARER without the
+	// built-in OIDC token retrieval.
+	OnTokenRefresh TokenRefresher
+	// QueueInterval is how often the queue length gauge is updated while no
+	// delivery reports arrive, default 5s.
+	QueueInterval time.Duration
+}
+
+// outgoing is stored as the opaque of every message sent through KafkaProducer.Send.
+type outgoing struct {
+	token any
+	sent  time.Time
+}
+
+func newOutgoing(ctx context.Context) *outgoing {
+	return &outgoing{token: ctx.Value(deliveryTokenKey{}), sent: time.Now()}
+}
+
+// delivered records the delivery report of msg and passes it to the handlers.
+func (k *KafkaProducer) delivered(msg *kafka.Message, handlers []DeliveryHandler, log *logrus.Logger) DeliveryReport {
+	r := DeliveryReport{
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Key:       msg.Key,
+		Err:       msg.TopicPartition.Error,
+	}
+	if msg.TopicPartition.Topic != nil {
+		r.Topic = *msg.TopicPartition.Topic
+	}
+	if o, ok := msg.Opaque.(*outgoing); ok {
+		r.Token = o.token
+		r.Latency = time.Since(o.sent)
+	}
+
+	entry := log.WithFields(logrus.Fields{
+		"topic":     r.Topic,
+		"partition": r.Partition,
+		"offset":    r.Offset,
+		"module":    "Kafka",
+	})
+	if r.Err != nil {
+		metrics.KafkaProducerFailed.WithLabelValues(r.Topic).Inc()
+		entry.WithError(r.Err).Error("Kafka delivery failed")
+	} else {
+		metrics.KafkaProducerDelivered.WithLabelValues(r.Topic).Inc()
+		metrics.KafkaProducerDeliveryDuration.WithLabelValues(r.Topic).Observe(r.Latency.Seconds())
+		entry.Debug("Kafka message delivered")
+	}
+
+	for _, h := range handlers {
+		h(r)
+	}
+	return r
+}
+
+func (k *KafkaProducer) refreshToken(ctx context.Context, e kafka.OAuthBearerTokenRefresh, refresh TokenRefresher, log *logrus.Logger) {
+	if refresh == nil {
+		log.WithField("module", "Kafka").Warn("Kafka OAUTHBEARER token refresh requested but no refresher is configured")
+		return
+	}
+
+	token, err := refresh(ctx, e.Config)
+	if err == nil {
+		err = k.p.SetOAuthBearerToken(token)
+	}
+	if err != nil {
+		log.WithError(err).WithField("module", "Kafka").Error("failed to refresh Kafka OAUTHBEARER token")
+		_ = k.p.SetOAuthBearerTokenFailure(err.Error()) //nolint:errcheck
+	}
+}
+
+func (k *KafkaProducer) observeQueue() {
+	metrics.KafkaProducerQueue.WithLabelValues(k.p.String()).Set(float64(k.p.Len()))
+}
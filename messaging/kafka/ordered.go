@@ -0,0 +1,299 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 10.30
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+// offsetTracker follows the unfinished messages of one partition. Messages finish
+// out of order, but only the offset after the longest finished prefix may be
+// committed.
+type offsetTracker struct {
+	pending []int64 // offsets in fetch order
+	done    map[int64]bool
+	paused  bool // paused for backpressure
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{done: make(map[int64]bool)}
+}
+
+func (t *offsetTracker) add(offset int64) {
+	t.pending = append(t.pending, offset)
+}
+
+// finish marks offset as handled. It returns the next offset to commit and true
+// when the finished prefix grew.
+func (t *offsetTracker) finish(offset int64) (int64, bool) {
+	t.done[offset] = true
+
+	var next int64
+	advanced := false
+	for len(t.pending) > 0 && t.done[t.pending[0]] {
+		delete(t.done, t.pending[0])
+		next = t.pending[0] + 1
+		t.pending = t.pending[1:]
+		advanced = true
+	}
+	return next, advanced
+}
+
+func (t *offsetTracker) inFlight() int {
+	return len(t.pending)
+}
+
+type job[T event.Event] struct {
+	msg     *kafka.Message
+	md      Metadata
+	evt     T
+	tracker *offsetTracker
+}
+
+type jobResult struct {
+	msg      *kafka.Message
+	tracker  *offsetTracker
+	finished bool // false when the handler was interrupted by shutdown
+}
+
+type workerPool[T event.Event] struct {
+	queues      []chan job[T]
+	results     chan jobResult
+	trackers    map[partitionKey]*offsetTracker
+	maxInFlight int
+	stopped     bool
+	wg          sync.WaitGroup
+}
+
+func newWorkerPool[T event.Event](concurrency, maxInFlight int) *workerPool[T] {
+	p := &workerPool[T]{
+		queues:      make([]chan job[T], concurrency),
+		results:     make(chan jobResult, concurrency),
+		trackers:    make(map[partitionKey]*offsetTracker),
+		maxInFlight: maxInFlight,
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan job[T], 64)
+	}
+	return p
+}
+
+func (p *workerPool[T]) start(ctx context.Context, work func(context.Context, job[T]) bool) {
+	for _, q := range p.queues {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for j := range q {
+				p.results <- jobResult{msg: j.msg, tracker: j.tracker, finished: work(ctx, j)}
+			}
+		}()
+	}
+}
+
+// queueFor picks the worker of a message. Keyless messages keep partition order.
+func (p *workerPool[T]) queueFor(msg *kafka.Message) chan job[T] {
+	h := fnv.New32a()
+	if len(msg.Key) > 0 {
+		_, _ = h.Write(msg.Key) //nolint:errcheck
+	} else {
+		tp := msg.TopicPartition
+		_, _ = h.Write([]byte(*tp.Topic + "/" + strconv.Itoa(int(tp.Partition)))) //nolint:errcheck
+	}
+	return p.queues[h.Sum32()%uint32(len(p.queues))]
+}
+
+func (p *workerPool[T]) tracker(key partitionKey) *offsetTracker {
+	t, ok := p.trackers[key]
+	if !ok {
+		t = newOffsetTracker()
+		p.trackers[key] = t
+	}
+	return t
+}
+
+// dispatch hands msg to the worker of its key. While the worker's queue is full,
+// finished results are drained so the poll loop never deadlocks.
+func (c *Consumer[T]) dispatch(msg *kafka.Message) {
+	key := partitionKey{*msg.TopicPartition.Topic, msg.TopicPartition.Partition}
+	t := c.workers.tracker(key)
+
+	evt, md, ok := c.prepare(msg, func(m *kafka.Message) {
+		t.add(int64(m.TopicPartition.Offset))
+		c.complete(jobResult{msg: m, tracker: t, finished: true})
+	})
+	if !ok {
+		return
+	}
+	delete(c.failures, key)
+	t.add(md.Offset)
+
+	q := c.workers.queueFor(msg)
+	for sent := false; !sent; {
+		select {
+		case q <- job[T]{msg: msg, md: md, evt: evt, tracker: t}:
+			sent = true
+		case r := <-c.workers.results:
+			c.complete(r)
+		}
+	}
+
+	if t.inFlight() >= c.workers.maxInFlight && !t.paused {
+		c.backpressure(msg, t)
+	}
+}
+
+// work runs one job on a worker goroutine. Without a retry policy a failed job is
+// retried in place, which holds back later messages of the same key only.
+func (c *Consumer[T]) work(ctx context.Context, j job[T]) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	md := j.md
+	for {
+		err := c.handle(ctx, j.evt, md)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if c.forward(j.msg, md, err) {
+			return true
+		}
+
+		delay := c.cfg.Backoff.Delay(md.Attempt)
+		c.entry(md).WithError(err).WithFields(logrus.Fields{
+			"attempt":  md.Attempt,
+			"retry_in": delay,
+		}).Warn("kafka handler failed, retrying")
+		if backoff.Sleep(ctx, delay) != nil {
+			return false
+		}
+		md.Attempt++
+	}
+}
+
+// complete records a job result and stores the partition's new commit offset.
+func (c *Consumer[T]) complete(r jobResult) {
+	if !r.finished {
+		return
+	}
+
+	tp := r.msg.TopicPartition
+	key := partitionKey{*tp.Topic, tp.Partition}
+	t := r.tracker
+	if c.workers.trackers[key] != t {
+		// The partition was revoked while the job ran.
+		return
+	}
+
+	if next, ok := t.finish(int64(tp.Offset)); ok {
+		tp.Offset = kafka.Offset(next)
+		c.store(tp)
+	}
+
+	if t.paused && t.inFlight() <= c.workers.maxInFlight/2 {
+		t.paused = false
+		if _, delayed := c.paused[key]; delayed {
+			return
+		}
+		if err := c.c.Resume([]kafka.TopicPartition{{Topic: tp.Topic, Partition: tp.Partition}}); err != nil {
+			c.log.WithError(err).WithField("module", "Kafka").Error("failed to resume kafka partition")
+			return
+		}
+		c.log.WithFields(logrus.Fields{
+			"topic":     *tp.Topic,
+			"partition": tp.Partition,
+			"in_flight": t.inFlight(),
+			"module":    "Kafka",
+		}).Debug("kafka partition resumed after backpressure")
+	}
+}
+
+// backpressure pauses the partition of msg until its in-flight messages drain.
+func (c *Consumer[T]) backpressure(msg *kafka.Message, t *offsetTracker) {
+	tp := kafka.TopicPartition{Topic: msg.TopicPartition.Topic, Partition: msg.TopicPartition.Partition}
+	if err := c.c.Pause([]kafka.TopicPartition{tp}); err != nil {
+		c.log.WithError(err).WithField("module", "Kafka").Error("failed to pause kafka partition")
+		return
+	}
+
+	// Messages fetched past msg are dropped by the pause; continue after msg.
+	tp.Offset = msg.TopicPartition.Offset + 1
+	if err := c.c.Seek(tp, 0); err != nil {
+		c.log.WithError(err).WithField("module", "Kafka").Error("failed to seek kafka partition")
+	}
+	t.paused = true
+	c.log.WithFields(logrus.Fields{
+		"topic":     *tp.Topic,
+		"partition": tp.Partition,
+		"offset":    tp.Offset,
+		"in_flight": t.inFlight(),
+		"module":    "Kafka",
+	}).Debug("kafka partition paused for backpressure")
+}
+
+func (c *Consumer[T]) trackerOf(key partitionKey) *offsetTracker {
+	if c.workers == nil {
+		return nil
+	}
+	return c.workers.trackers[key]
+}
+
+func (c *Consumer[T]) drainResults() {
+	for {
+		select {
+		case r := <-c.workers.results:
+			c.complete(r)
+		default:
+			return
+		}
+	}
+}
+
+// stopWorkers waits for the workers to drain their queues and records every
+// result. Jobs still queued at shutdown are left unfinished and redelivered.
+func (c *Consumer[T]) stopWorkers() {
+	c.workers.stopped = true
+	for _, q := range c.workers.queues {
+		close(q)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.workers.wg.Wait()
+		close(done)
+	}()
+
+	for {
+		select {
+		case r := <-c.workers.results:
+			c.complete(r)
+		case <-done:
+			c.drainResults()
+			return
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 11.15
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	tr := newOffsetTracker()
+	for _, off := range []int64{5, 6, 7, 9} {
+		tr.add(off)
+	}
+
+	if _, ok := tr.finish(6); ok {
+		t.Errorf("Expected no progress while 5 is unfinished")
+	}
+	if next, ok := tr.finish(5); !ok || next != 7 {
+		t.Errorf("Expected next offset 7, got %d (%v)", next, ok)
+	}
+	if _, ok := tr.finish(9); ok {
+		t.Errorf("Expected no progress while 7 is unfinished")
+	}
+	if next, ok := tr.finish(7); !ok || next != 10 {
+		t.Errorf("Expected next offset 10, got %d (%v)", next, ok)
+	}
+	if n := tr.inFlight(); n != 0 {
+		t.Errorf("Expected nothing in flight, got %d", n)
+	}
+}
+
+func TestConsumer_ConcurrentKeepsKeyOrder(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+	brokers := mc.BootstrapServers()
+
+	keys := []string{"a", "b", "c", "d"}
+	var events []deviceEvent
+	for i := range 40 {
+		events = append(events, deviceEvent{ID: strconv.Itoa(i), JID: keys[i%len(keys)]})
+	}
+	produceEvents(t, brokers, "devices", events...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	total := 0
+	var running, peak atomic.Int32
+
+	handler := HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		id, _ := strconv.Atoi(evt.ID) //nolint:errcheck
+		mu.Lock()
+		defer mu.Unlock()
+		seen[evt.JID] = append(seen[evt.JID], id)
+		if total++; total == len(events) {
+			time.AfterFunc(100*time.Millisecond, cancel)
+		}
+		return nil
+	})
+
+	c, err := NewConsumer(ConsumerConfig{
+		Brokers:     []string{brokers},
+		GroupID:     "test",
+		Topics:      []string{"devices"},
+		Options:     map[string]any{"auto.offset.reset": "earliest"},
+		Concurrency: 4,
+		MaxInFlight: 8,
+	}, handler, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if total != len(events) {
+		t.Fatalf("Expected %d events, got %d", len(events), total)
+	}
+	for key, ids := range seen {
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Errorf("Key %s: expected events in order, got %v", key, ids)
+				break
+			}
+		}
+	}
+	if peak.Load() < 2 {
+		t.Errorf("Expected keys to be handled in parallel, peak concurrency %d", peak.Load())
+	}
+	if off := committedOffset(t, brokers, "test", "devices"); off != 40 {
+		t.Errorf("Expected committed offset 40, got %v", off)
+	}
+}
+
+func TestConsumer_BackpressurePausesAndResumes(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+	brokers := mc.BootstrapServers()
+
+	var events []deviceEvent
+	for i := range 20 {
+		events = append(events, deviceEvent{ID: strconv.Itoa(i), JID: []string{"a", "b"}[i%2]})
+	}
+	produceEvents(t, brokers, "devices", events...)
+
+	log, hook := test.NewNullLogger()
+	log.SetLevel(logrus.DebugLevel)
+	logged := func(msg string) int {
+		for i, e := range hook.AllEntries() {
+			if e.Message == msg {
+				return i
+			}
+		}
+		return -1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Handlers block until the partition has been paused, so the in-flight limit
+	// is reached with messages still fetched past it.
+	gate := make(chan struct{})
+	go func() {
+		for logged("kafka partition paused for backpressure") < 0 && ctx.Err() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+		close(gate)
+	}()
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	handler := HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		select {
+		case <-gate:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		seen[evt.ID]++
+		if len(seen) == len(events) {
+			time.AfterFunc(100*time.Millisecond, cancel)
+		}
+		return nil
+	})
+
+	c, err := NewConsumer(ConsumerConfig{
+		Brokers:     []string{brokers},
+		GroupID:     "test",
+		Topics:      []string{"devices"},
+		Options:     map[string]any{"auto.offset.reset": "earliest"},
+		Concurrency: 2,
+		MaxInFlight: 2,
+	}, handler, log)
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	paused, resumed := logged("kafka partition paused for backpressure"), logged("kafka partition resumed after backpressure")
+	if paused < 0 || resumed < paused {
+		t.Errorf("Expected a pause followed by a resume, got entries %d and %d", paused, resumed)
+	}
+	for _, evt := range events {
+		if n := seen[evt.ID]; n != 1 {
+			t.Errorf("Expected event %s once after the seek, got %d", evt.ID, n)
+		}
+	}
+	if off := committedOffset(t, brokers, "test", "devices"); off != 20 {
+		t.Errorf("Expected committed offset 20, got %v", off)
+	}
+}
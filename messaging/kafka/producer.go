@@ -17,10 +17,16 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
 
+	"github.com/PakaiWA/pakaiwa-platform/messaging/codec"
 	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
 	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/observability/metrics"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/sirupsen/logrus"
 )
@@ -43,23 +49,61 @@ func NewKafkaProducer(cfg *kafka.ConfigMap, log *logrus.Logger) producer.Message
 	}
 }
 
-func (k *KafkaProducer) Send(_ context.Context, topic string, key []byte, clientJID []byte, value []byte) error {
-	msg := &kafka.Message{
+func (k *KafkaProducer) Send(ctx context.Context, msg producer.Message) error {
+	return k.produce(newMessage(ctx, msg), nil)
+}
+
+// SendSync sends a message and waits for the broker to acknowledge it, returning
+// the partition and offset it was written to. Its delivery report goes to SendSync
+// only, not to the poll loop. When ctx ends first the message may still be
+// delivered.
+func (k *KafkaProducer) SendSync(ctx context.Context, msg producer.Message) (int32, int64, error) {
+	delivery := make(chan kafka.Event, 1)
+	if err := k.produce(newMessage(ctx, msg), delivery); err != nil {
+		return 0, 0, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
+	case ev := <-delivery:
+		m, ok := ev.(*kafka.Message)
+		if !ok {
+			return 0, 0, fmt.Errorf("kafka: unexpected delivery event %v", ev)
+		}
+		r := k.delivered(m, nil, k.log)
+		return r.Partition, r.Offset, r.Err
+	}
+}
+
+func newMessage(ctx context.Context, msg producer.Message) *kafka.Message {
+	topic := msg.Topic
+	partition := kafka.PartitionAny
+	if msg.Partition != nil {
+		partition = *msg.Partition
+	}
+
+	headers := producer.Headers(ctx, msg)
+	kh := make([]kafka.Header, 0, len(headers))
+	for _, k := range slices.Sorted(maps.Keys(headers)) {
+		kh = append(kh, kafka.Header{Key: k, Value: []byte(headers[k])})
+	}
+
+	return &kafka.Message{
 		TopicPartition: kafka.TopicPartition{
 			Topic:     &topic,
-			Partition: kafka.PartitionAny,
-		},
-		Key:   key,
-		Value: value,
-		Headers: []kafka.Header{
-			{
-				Key:   "device_id",
-				Value: clientJID,
-			},
+			Partition: partition,
 		},
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Headers:   kh,
+		Timestamp: msg.Timestamp,
+		Opaque:    newOutgoing(ctx),
 	}
+}
 
-	err := k.p.Produce(msg, nil)
+func (k *KafkaProducer) produce(msg *kafka.Message, delivery chan kafka.Event) error {
+	err := k.p.Produce(msg, delivery)
 	if err != nil {
 		if kafkaErr, ok := err.(kafka.Error); ok && kafkaErr.Code() == kafka.ErrQueueFull {
 			// Jika queue penuh, kita bisa push synchronously atau biarkan caller handle.
@@ -93,28 +137,72 @@ type Producer[T event.Event] struct {
 	Producer producer.MessageProducer
 	Topic    string
 	Log      *logrus.Logger
+	// Codec encodes events, default codec.JSON.
+	Codec codec.Codec
+	// Compression, when set, compresses large encoded events.
+	Compression *codec.Compression
+	// CloudEvents, when set, sends events as CloudEvents 1.0 in its content mode.
+	// CloudEvents data is always JSON, so Codec and Compression are not used.
+	CloudEvents *event.CloudEventConfig
 }
 
+// Send sends evt keyed by its EventKey. A non-empty clientJID is sent as the
+// device_id header.
 func (p *Producer[T]) Send(ctx context.Context, evt T, clientJID string) error {
-	value, err := json.Marshal(evt)
+	msg, err := p.message(evt, clientJID)
 	if err != nil {
-		p.Log.WithError(err).Error("failed to marshal event")
 		return err
 	}
+	return p.Producer.Send(ctx, msg)
+}
+
+// SyncSender is producer.SyncSender, kept here for existing callers.
+type SyncSender = producer.SyncSender
 
-	return p.Producer.Send(
-		ctx,
-		p.Topic,
-		[]byte(evt.EventKey()),
-		[]byte(clientJID),
-		value,
-	)
+// SendSync sends evt and waits for the broker to acknowledge it. The underlying
+// producer must implement SyncSender.
+func (p *Producer[T]) SendSync(ctx context.Context, evt T, clientJID string) (int32, int64, error) {
+	sp, ok := p.Producer.(SyncSender)
+	if !ok {
+		return 0, 0, errors.New("kafka: producer does not support SendSync")
+	}
+
+	msg, err := p.message(evt, clientJID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return sp.SendSync(ctx, msg)
+}
+
+func (p *Producer[T]) message(evt T, clientJID string) (producer.Message, error) {
+	var msg producer.Message
+	var err error
+	if p.CloudEvents != nil {
+		msg, err = producer.CloudEventMessage(p.Topic, evt, *p.CloudEvents)
+	} else {
+		c := p.Codec
+		if c == nil {
+			c = codec.JSON{}
+		}
+		msg, err = producer.EncodeMessage(p.Topic, evt, c, p.Compression)
+	}
+	if err != nil {
+		p.Log.WithError(err).Error("failed to marshal event")
+		return msg, err
+	}
+	if clientJID != "" {
+		msg.Headers[producer.HeaderDeviceID] = clientJID
+	}
+	return msg, nil
 }
 
+// StartProducerPollLoop handles the events of a Kafka producer until ctx is
+// canceled: delivery reports, statistics and OAUTHBEARER token refreshes.
 func StartProducerPollLoop(
 	ctx context.Context,
 	producer producer.MessageProducer,
 	log *logrus.Logger,
+	opts ...PollOptions,
 ) {
 	kp, ok := producer.(*KafkaProducer)
 	if !ok {
@@ -122,36 +210,44 @@ func StartProducerPollLoop(
 		return
 	}
 
+	var o PollOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.QueueInterval <= 0 {
+		o.QueueInterval = 5 * time.Second
+	}
+
 	go func() {
 		log.Info("Kafka producer poll loop started")
 
+		ticker := time.NewTicker(o.QueueInterval)
+		defer ticker.Stop()
+
 		for {
 			select {
 			case <-ctx.Done():
 				log.Info("Kafka producer poll loop stopping")
 				return
 
+			case <-ticker.C:
+				kp.observeQueue()
+
 			case ev := <-kp.Events():
 				switch e := ev.(type) {
 
 				case *kafka.Message:
-					if e.TopicPartition.Error != nil {
-						log.WithFields(logrus.Fields{
-							"topic":     *e.TopicPartition.Topic,
-							"partition": e.TopicPartition.Partition,
-							"offset":    e.TopicPartition.Offset,
-							"module":    "Kafka",
-						}).WithError(e.TopicPartition.Error).
-							Error("Kafka delivery failed")
-					} else {
-						log.WithFields(logrus.Fields{
-							"topic":     *e.TopicPartition.Topic,
-							"partition": e.TopicPartition.Partition,
-							"offset":    e.TopicPartition.Offset,
-							"module":    "Kafka",
-						}).Debug("Kafka message delivered")
+					kp.delivered(e, o.OnDelivery, log)
+					kp.observeQueue()
+
+				case *kafka.Stats:
+					if err := metrics.ObserveKafkaStats("", e.String()); err != nil {
+						log.WithError(err).WithField("module", "Kafka").Warn("failed to parse kafka statistics")
 					}
 
+				case kafka.OAuthBearerTokenRefresh:
+					kp.refreshToken(ctx, e, o.OnTokenRefresh, log)
+
 				case kafka.Error:
 					log.WithError(e).Error("Kafka error")
 
@@ -0,0 +1,261 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 17.20
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/observability/logging/ctxmeta"
+	"github.com/PakaiWA/pakaiwa-platform/observability/metrics"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestStartProducerPollLoop_DeliveryReports(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+
+	p := NewKafkaProducer(&kafka.ConfigMap{
+		"bootstrap.servers":      mc.BootstrapServers(),
+		"message.timeout.ms":     1000,
+		"statistics.interval.ms": 100,
+	}, logrus.New())
+	if p == nil {
+		t.Fatal("Failed to create producer")
+	}
+	defer p.Close() //nolint:errcheck
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reports := make(chan DeliveryReport, 4)
+	StartProducerPollLoop(ctx, p, logrus.New(), PollOptions{
+		OnDelivery: []DeliveryHandler{func(r DeliveryReport) { reports <- r }},
+	})
+
+	delivered := testutil.ToFloat64(metrics.KafkaProducerDelivered.WithLabelValues("devices"))
+	failed := testutil.ToFloat64(metrics.KafkaProducerFailed.WithLabelValues("devices"))
+
+	if err := p.Send(WithDeliveryToken(ctx, 42), producer.Message{Topic: "devices", Key: []byte("a"), Value: []byte("{}")}); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	r := <-reports
+	if r.Err != nil || r.Token != 42 || r.Topic != "devices" || string(r.Key) != "a" || r.Offset != 0 {
+		t.Errorf("Expected delivered report with token 42, got %+v", r)
+	}
+	if r.Latency <= 0 {
+		t.Errorf("Expected positive latency, got %v", r.Latency)
+	}
+
+	if err := mc.SetBrokerDown(1); err != nil {
+		t.Fatalf("Failed to stop broker: %v", err)
+	}
+	if err := p.Send(WithDeliveryToken(ctx, "lost"), producer.Message{Topic: "devices", Key: []byte("b"), Value: []byte("{}")}); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	select {
+	case r = <-reports:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected a failed delivery report")
+	}
+	if r.Err == nil || r.Token != "lost" {
+		t.Errorf("Expected failed report with token lost, got %+v", r)
+	}
+
+	if d := testutil.ToFloat64(metrics.KafkaProducerDelivered.WithLabelValues("devices")) - delivered; d != 1 {
+		t.Errorf("Expected 1 delivered message, got %v", d)
+	}
+	if d := testutil.ToFloat64(metrics.KafkaProducerFailed.WithLabelValues("devices")) - failed; d != 1 {
+		t.Errorf("Expected 1 failed message, got %v", d)
+	}
+	if n := testutil.CollectAndCount(metrics.KafkaClientQueueMessages); n == 0 {
+		t.Error("Expected producer statistics to be exported")
+	}
+}
+
+func TestKafkaProducer_SendSync(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+
+	mp := NewKafkaProducer(&kafka.ConfigMap{
+		"bootstrap.servers":  mc.BootstrapServers(),
+		"message.timeout.ms": 1000,
+	}, logrus.New())
+	if mp == nil {
+		t.Fatal("Failed to create producer")
+	}
+	defer mp.Close() //nolint:errcheck
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reports := make(chan DeliveryReport, 4)
+	StartProducerPollLoop(ctx, mp, logrus.New(), PollOptions{
+		OnDelivery: []DeliveryHandler{func(r DeliveryReport) { reports <- r }},
+	})
+
+	p := &Producer[deviceEvent]{Producer: mp, Topic: "devices", Log: logrus.New()}
+	traced := ctxmeta.WithTraceID(ctx, "trace-1")
+	for i := range 2 {
+		partition, offset, err := p.SendSync(traced, deviceEvent{ID: "1", JID: "a"}, "jid")
+		if err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		if partition != 0 || offset != int64(i) {
+			t.Errorf("Expected partition 0 offset %d, got %d/%d", i, partition, offset)
+		}
+	}
+
+	md := readMessage(t, mc.BootstrapServers(), "devices", 0)
+	want := map[string]string{
+		producer.HeaderEventName:   "device.updated",
+		producer.HeaderContentType: producer.ContentTypeJSON,
+		producer.HeaderTraceID:     "trace-1",
+		producer.HeaderDeviceID:    "jid",
+	}
+	if !maps.Equal(md.Headers, want) {
+		t.Errorf("Expected headers %v, got %v", want, md.Headers)
+	}
+	if string(md.Key) != "a" {
+		t.Errorf("Expected key a, got %s", md.Key)
+	}
+
+	if err := mc.SetBrokerDown(1); err != nil {
+		t.Fatalf("Failed to stop broker: %v", err)
+	}
+	if _, _, err := p.SendSync(ctx, deviceEvent{ID: "2", JID: "a"}, "jid"); err == nil {
+		t.Error("Expected delivery error while the broker is down")
+	}
+
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	if _, _, err := p.SendSync(short, deviceEvent{ID: "3", JID: "a"}, "jid"); err != context.DeadlineExceeded {
+		t.Errorf("Expected context deadline, got %v", err)
+	}
+
+	select {
+	case r := <-reports:
+		t.Errorf("Expected no report on the poll loop, got %+v", r)
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
+
+func TestKafkaProducer_MessageFields(t *testing.T) {
+	mc := newMockCluster(t)
+	if err := mc.CreateTopic("devices", 2, 1); err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+
+	mp := NewKafkaProducer(&kafka.ConfigMap{"bootstrap.servers": mc.BootstrapServers()}, logrus.New())
+	if mp == nil {
+		t.Fatal("Failed to create producer")
+	}
+	defer mp.Close() //nolint:errcheck
+
+	partition := int32(1)
+	ts := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	got, _, err := mp.(*KafkaProducer).SendSync(context.Background(), producer.Message{
+		Topic:     "devices",
+		Key:       []byte("a"),
+		Value:     []byte("{}"),
+		Headers:   map[string]string{"tenant_id": "t1"},
+		Timestamp: ts,
+		Partition: &partition,
+	})
+	if err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if got != partition {
+		t.Errorf("Expected partition %d, got %d", partition, got)
+	}
+
+	md := readMessage(t, mc.BootstrapServers(), "devices", partition)
+	if md.Headers["tenant_id"] != "t1" || len(md.Headers) != 1 {
+		t.Errorf("Expected only the tenant_id header, got %v", md.Headers)
+	}
+	if !md.Timestamp.Equal(ts) {
+		t.Errorf("Expected timestamp %v, got %v", ts, md.Timestamp)
+	}
+}
+
+func TestProducer_CloudEvents(t *testing.T) {
+	mc := newMockCluster(t, "devices")
+
+	mp := NewKafkaProducer(&kafka.ConfigMap{"bootstrap.servers": mc.BootstrapServers()}, logrus.New())
+	if mp == nil {
+		t.Fatal("Failed to create producer")
+	}
+	defer mp.Close() //nolint:errcheck
+
+	p := &Producer[deviceEvent]{
+		Producer:    mp,
+		Topic:       "devices",
+		Log:         logrus.New(),
+		CloudEvents: &event.CloudEventConfig{Source: "/pakaiwa/test"},
+	}
+	if _, _, err := p.SendSync(context.Background(), deviceEvent{ID: "1", JID: "a"}, "jid"); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+
+	md := readMessage(t, mc.BootstrapServers(), "devices", 0)
+	want := map[string]string{
+		"ce_specversion":                  "1.0",
+		"ce_id":                           "1",
+		"ce_source":                       "/pakaiwa/test",
+		"ce_type":                         "device.updated",
+		producer.HeaderCloudEventsContent: "application/json",
+		producer.HeaderContentType:        "application/json",
+		producer.HeaderEventName:          "device.updated",
+		producer.HeaderDeviceID:           "jid",
+	}
+	for k, v := range want {
+		if md.Headers[k] != v {
+			t.Errorf("Expected header %s %q, got %q", k, v, md.Headers[k])
+		}
+	}
+	if md.Headers["ce_time"] == "" {
+		t.Error("Expected the ce_time header")
+	}
+	if string(md.Key) != "a" {
+		t.Errorf("Expected key a, got %s", md.Key)
+	}
+}
+
+// readMessage returns the first message of a partition.
+func readMessage(t *testing.T, brokers, topic string, partition int32) Metadata {
+	t.Helper()
+
+	c, err := NewKafkaConsumer(ConsumerConfig{Brokers: []string{brokers}, GroupID: "reader"})
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer func() {
+		_ = c.Close() //nolint:errcheck
+	}()
+
+	if err := c.Assign([]kafka.TopicPartition{{Topic: &topic, Partition: partition, Offset: kafka.OffsetBeginning}}); err != nil {
+		t.Fatalf("Failed to assign: %v", err)
+	}
+	msg, err := c.ReadMessage(10 * time.Second)
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	return metadataOf(msg)
+}
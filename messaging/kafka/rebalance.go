@@ -0,0 +1,187 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 13.10
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/observability/metrics"
+	"github.com/PakaiWA/pakaiwa-platform/runtime/shutdown"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+// RunUntilSignal runs the consumer until ctx is canceled or one of sigs arrives
+// (default SIGINT and SIGTERM). Handled offsets are committed and the group is
+// left before it returns.
+func (c *Consumer[T]) RunUntilSignal(ctx context.Context, sigs ...os.Signal) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		if sig := shutdown.Wait(ctx, sigs...); sig != nil {
+			c.log.WithFields(logrus.Fields{
+				"signal": sig.String(),
+				"module": "Kafka",
+			}).Info("Kafka consumer received shutdown signal")
+		}
+		cancel()
+	}()
+
+	return c.Run(ctx)
+}
+
+// rebalance is called on the polling goroutine when the group assigns or revokes
+// partitions. Both the eager and the cooperative (cooperative-sticky) protocols
+// are supported.
+func (c *Consumer[T]) rebalance(_ *kafka.Consumer, e kafka.Event) error {
+	switch e := e.(type) {
+	case kafka.AssignedPartitions:
+		return c.assigned(e.Partitions)
+	case kafka.RevokedPartitions:
+		return c.revoked(e.Partitions)
+	}
+	return nil
+}
+
+func (c *Consumer[T]) cooperative() bool {
+	return c.c.GetRebalanceProtocol() == "COOPERATIVE"
+}
+
+func (c *Consumer[T]) assigned(partitions []kafka.TopicPartition) error {
+	var err error
+	if c.cooperative() {
+		err = c.c.IncrementalAssign(partitions)
+	} else {
+		err = c.c.Assign(partitions)
+	}
+	if err != nil {
+		c.log.WithError(err).WithField("module", "Kafka").Error("failed to assign kafka partitions")
+		return err
+	}
+
+	metrics.KafkaRebalances.WithLabelValues(c.cfg.GroupID, "assigned").Inc()
+	c.log.WithFields(logrus.Fields{
+		"partitions": partitionNames(partitions),
+		"module":     "Kafka",
+	}).Info("Kafka partitions assigned")
+
+	if c.cfg.OnAssigned != nil {
+		c.cfg.OnAssigned(partitions)
+	}
+	return nil
+}
+
+// revoked lets the handlers of the revoked partitions finish and commits their
+// offsets before giving the partitions up, so the next owner continues where this
+// consumer stopped. When the assignment was lost the offsets can no longer be
+// committed and the next owner redelivers them.
+func (c *Consumer[T]) revoked(partitions []kafka.TopicPartition) error {
+	lost := c.c.AssignmentLost()
+
+	if c.workers != nil {
+		c.awaitRevoked(partitions)
+	}
+	if lost {
+		c.log.WithField("module", "Kafka").Warn("Kafka partition assignment lost, offsets not committed")
+	} else {
+		c.commitWG.Wait()
+		c.commit()
+	}
+
+	if c.cfg.OnRevoked != nil {
+		c.cfg.OnRevoked(partitions)
+	}
+
+	var err error
+	if c.cooperative() {
+		err = c.c.IncrementalUnassign(partitions)
+	} else {
+		err = c.c.Unassign()
+	}
+	c.forget(partitions)
+
+	if err != nil {
+		c.log.WithError(err).WithField("module", "Kafka").Error("failed to unassign kafka partitions")
+		return err
+	}
+
+	metrics.KafkaRebalances.WithLabelValues(c.cfg.GroupID, "revoked").Inc()
+	c.log.WithFields(logrus.Fields{
+		"partitions": partitionNames(partitions),
+		"lost":       lost,
+		"module":     "Kafka",
+	}).Info("Kafka partitions revoked")
+	return nil
+}
+
+// forget drops the redelivery, pause, offset and lag state of revoked partitions.
+func (c *Consumer[T]) forget(partitions []kafka.TopicPartition) {
+	for _, tp := range partitions {
+		key := partitionKey{*tp.Topic, tp.Partition}
+		delete(c.failures, key)
+		delete(c.paused, key)
+		metrics.ForgetKafkaPartition(c.cfg.GroupID, key.topic, key.partition)
+		if c.workers != nil {
+			delete(c.workers.trackers, key)
+		}
+	}
+}
+
+// awaitRevoked records worker results until no message of the revoked partitions
+// is in flight, or RevokeTimeout passes.
+func (c *Consumer[T]) awaitRevoked(partitions []kafka.TopicPartition) {
+	if c.workers.stopped {
+		return
+	}
+
+	timeout := time.NewTimer(c.cfg.RevokeTimeout)
+	defer timeout.Stop()
+
+	for c.inFlight(partitions) > 0 {
+		select {
+		case r := <-c.workers.results:
+			c.complete(r)
+		case <-timeout.C:
+			c.log.WithFields(logrus.Fields{
+				"in_flight": c.inFlight(partitions),
+				"module":    "Kafka",
+			}).Warn("Kafka handlers of revoked partitions still running, their messages will be redelivered")
+			return
+		}
+	}
+}
+
+func (c *Consumer[T]) inFlight(partitions []kafka.TopicPartition) int {
+	n := 0
+	for _, tp := range partitions {
+		if t := c.workers.trackers[partitionKey{*tp.Topic, tp.Partition}]; t != nil {
+			n += t.inFlight()
+		}
+	}
+	return n
+}
+
+func partitionNames(partitions []kafka.TopicPartition) []string {
+	names := make([]string, len(partitions))
+	for i, tp := range partitions {
+		names[i] = *tp.Topic + "[" + strconv.Itoa(int(tp.Partition)) + "]"
+	}
+	return names
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 14.00
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+func TestConsumer_CommitsBeforeRevoke(t *testing.T) {
+	for _, strategy := range []string{"range", "cooperative-sticky"} {
+		for _, concurrency := range []int{1, 4} {
+			mc := newMockCluster(t, "devices")
+			brokers := mc.BootstrapServers()
+			produceEvents(t, brokers, "devices",
+				deviceEvent{ID: "1", JID: "a"},
+				deviceEvent{ID: "2", JID: "b"},
+				deviceEvent{ID: "3", JID: "c"},
+			)
+
+			var assigned, revoked []kafka.TopicPartition
+			var committed kafka.Offset
+			var mu sync.Mutex
+			handled := 0
+
+			handler := HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+				mu.Lock()
+				defer mu.Unlock()
+				if handled++; handled == 3 {
+					proc, _ := os.FindProcess(os.Getpid()) //nolint:errcheck
+					_ = proc.Signal(syscall.SIGUSR1)       //nolint:errcheck
+				}
+				return nil
+			})
+			c, err := NewConsumer(ConsumerConfig{
+				Brokers: []string{brokers},
+				GroupID: "test",
+				Topics:  []string{"devices"},
+				Options: map[string]any{
+					"auto.offset.reset":             "earliest",
+					"partition.assignment.strategy": strategy,
+				},
+				Commit:      CommitPeriodic,
+				Concurrency: concurrency,
+				OnAssigned: func(partitions []kafka.TopicPartition) {
+					assigned = append(assigned, partitions...)
+				},
+				OnRevoked: func(partitions []kafka.TopicPartition) {
+					revoked = append(revoked, partitions...)
+					committed = committedOffset(t, brokers, "test", "devices")
+				},
+			}, handler, logrus.New())
+			if err != nil {
+				t.Fatalf("Failed to create consumer: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := c.RunUntilSignal(ctx, syscall.SIGUSR1); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ctx.Err() != nil {
+				t.Errorf("%s/%d: expected the signal to stop the consumer before the timeout", strategy, concurrency)
+			}
+			cancel()
+
+			if len(assigned) != 1 || *assigned[0].Topic != "devices" {
+				t.Errorf("%s/%d: expected devices to be assigned, got %v", strategy, concurrency, assigned)
+			}
+			if len(revoked) != 1 {
+				t.Errorf("%s/%d: expected devices to be revoked on close, got %v", strategy, concurrency, revoked)
+			}
+			if committed != 3 {
+				t.Errorf("%s/%d: expected offset 3 committed before revoke, got %v", strategy, concurrency, committed)
+			}
+		}
+	}
+}
+
+type consumerName struct{}
+
+func TestConsumer_RebalanceWaitsForHandlers(t *testing.T) {
+	mc := newMockCluster(t)
+	if err := mc.CreateTopic("devices", 2, 1); err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+	brokers := mc.BootstrapServers()
+
+	var events []deviceEvent
+	for i := range 100 {
+		events = append(events, deviceEvent{ID: strconv.Itoa(i), JID: strconv.Itoa(i % 5)})
+	}
+	produceEvents(t, brokers, "devices", events...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	handledBy := make(map[string]int)
+	running := map[string]*atomic.Int32{"first": {}, "second": {}}
+	revokes := make(map[string]int)
+	joined := make(chan struct{})
+
+	handler := HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		name := ctx.Value(consumerName{}).(string)
+		running[name].Add(1)
+		defer running[name].Add(-1)
+		time.Sleep(100 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		seen[evt.ID]++
+		handledBy[name]++
+		if len(seen) == 3 {
+			close(joined)
+		}
+		if len(seen) == len(events) {
+			cancel()
+		}
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	run := func(name string) {
+		defer wg.Done()
+		c, err := NewConsumer(ConsumerConfig{
+			Brokers: []string{brokers},
+			GroupID: "test",
+			Topics:  []string{"devices"},
+			Options: map[string]any{
+				"auto.offset.reset":     "earliest",
+				"session.timeout.ms":    6000,
+				"heartbeat.interval.ms": 500,
+				"queued.min.messages":   10,
+			},
+			Concurrency: 4,
+			MaxInFlight: 8,
+			OnRevoked: func(partitions []kafka.TopicPartition) {
+				if n := running[name].Load(); n != 0 {
+					t.Errorf("Expected handlers of %s to finish before revoke, %d running", name, n)
+				}
+				mu.Lock()
+				revokes[name]++
+				mu.Unlock()
+			},
+		}, handler, logrus.New())
+		if err != nil {
+			t.Errorf("Failed to create consumer: %v", err)
+			return
+		}
+		if err := c.Run(context.WithValue(ctx, consumerName{}, name)); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	wg.Add(2)
+	go run("first")
+	<-joined
+	go run("second")
+	wg.Wait()
+
+	// The first consumer gives its partitions up when the second joins, and both
+	// give theirs up on close.
+	if revokes["first"] != 2 || revokes["second"] != 1 {
+		t.Errorf("Expected 2 and 1 revokes, got %v", revokes)
+	}
+	if handledBy["first"] == 0 || handledBy["second"] == 0 {
+		t.Errorf("Expected both consumers to handle events, got %v", handledBy)
+	}
+	if len(seen) != len(events) {
+		t.Errorf("Expected %d events, got %d", len(events), len(seen))
+	}
+}
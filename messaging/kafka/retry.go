@@ -0,0 +1,328 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 08.20
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+// Headers set on messages moved to a retry topic or the DLQ.
+const (
+	HeaderOriginalTopic     = "original_topic"
+	HeaderOriginalPartition = "original_partition"
+	HeaderOriginalOffset    = "original_offset"
+	HeaderError             = "error"
+	HeaderAttempt           = "attempt" // failed attempts so far
+)
+
+// RetryPolicy moves failed messages to delayed retry topics and finally to a
+// dead-letter topic, so a poison message no longer blocks its partition. Retried
+// messages lose their order relative to the rest of the partition.
+//
+// For a source topic "orders" and Delays {1m, 10m} the topics are
+// "orders.retry.1m", "orders.retry.10m" and "orders.dlq". They must exist.
+type RetryPolicy struct {
+	Delays []time.Duration
+	// ProducerOptions configure the producer that writes retry and DLQ messages.
+	// bootstrap.servers defaults to the consumer's brokers.
+	ProducerOptions map[string]any
+}
+
+// RetryTopic returns the retry topic of topic for delay, e.g. "orders.retry.10m".
+func RetryTopic(topic string, delay time.Duration) string {
+	var suffix string
+	switch {
+	case delay%time.Hour == 0:
+		suffix = strconv.FormatInt(int64(delay/time.Hour), 10) + "h"
+	case delay%time.Minute == 0:
+		suffix = strconv.FormatInt(int64(delay/time.Minute), 10) + "m"
+	case delay%time.Second == 0:
+		suffix = strconv.FormatInt(int64(delay/time.Second), 10) + "s"
+	default:
+		suffix = strconv.FormatInt(delay.Milliseconds(), 10) + "ms"
+	}
+	return topic + ".retry." + suffix
+}
+
+// DLQTopic returns the dead-letter topic of topic.
+func DLQTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+type retrier struct {
+	policy   RetryPolicy
+	producer *kafka.Producer
+	delays   map[string]time.Duration // retry topic -> delay
+	send     func(msg *kafka.Message) error
+}
+
+func newRetrier(cfg ConsumerConfig) (*retrier, error) {
+	m := &kafka.ConfigMap{
+		"bootstrap.servers":  strings.Join(cfg.Brokers, ","),
+		"enable.idempotence": true,
+	}
+	for k, v := range cfg.Retry.ProducerOptions {
+		_ = m.SetKey(k, v) //nolint:errcheck
+	}
+
+	p, err := kafka.NewProducer(m)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &retrier{policy: *cfg.Retry, producer: p, delays: make(map[string]time.Duration)}
+	r.send = func(msg *kafka.Message) error { return produceSync(p, msg) }
+	for _, topic := range cfg.Topics {
+		for _, d := range cfg.Retry.Delays {
+			r.delays[RetryTopic(topic, d)] = d
+		}
+	}
+	return r, nil
+}
+
+// topics returns the retry topics the consumer subscribes to besides its own.
+func (r *retrier) topics() []string {
+	topics := make([]string, 0, len(r.delays))
+	for t := range r.delays {
+		topics = append(topics, t)
+	}
+	return topics
+}
+
+// forward publishes msg to its next retry topic, or the DLQ once every delay is
+// used or when dead is set, and waits for the broker to acknowledge it.
+// md.Attempt is the attempt that just failed.
+func (r *retrier) forward(msg *kafka.Message, md Metadata, cause error, dead bool) (string, error) {
+	origin := md.Topic
+	partition := strconv.Itoa(int(md.Partition))
+	offset := strconv.FormatInt(md.Offset, 10)
+	if v, ok := md.Headers[HeaderOriginalTopic]; ok {
+		origin = v
+		partition = md.Headers[HeaderOriginalPartition]
+		offset = md.Headers[HeaderOriginalOffset]
+	}
+
+	target := DLQTopic(origin)
+	if !dead && md.Attempt <= len(r.policy.Delays) {
+		target = RetryTopic(origin, r.policy.Delays[md.Attempt-1])
+	}
+
+	headers := withHeaders(msg.Headers, map[string]string{
+		HeaderOriginalTopic:     origin,
+		HeaderOriginalPartition: partition,
+		HeaderOriginalOffset:    offset,
+		HeaderError:             cause.Error(),
+		HeaderAttempt:           strconv.Itoa(md.Attempt),
+	})
+
+	return target, r.send(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &target, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+	})
+}
+
+// due reports when a message of a retry topic may be handled.
+func (r *retrier) due(md Metadata) time.Time {
+	delay, ok := r.delays[md.Topic]
+	if !ok || md.Timestamp.IsZero() {
+		return time.Time{}
+	}
+	return md.Timestamp.Add(delay)
+}
+
+func (r *retrier) close() {
+	r.producer.Flush(5000)
+	r.producer.Close()
+}
+
+// withHeaders returns headers with set applied, replacing existing keys.
+func withHeaders(headers []kafka.Header, set map[string]string) []kafka.Header {
+	out := make([]kafka.Header, 0, len(headers)+len(set))
+	for _, h := range headers {
+		if _, ok := set[h.Key]; !ok {
+			out = append(out, h)
+		}
+	}
+	for k, v := range set {
+		out = append(out, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return out
+}
+
+func produceSync(p *kafka.Producer, msg *kafka.Message) error {
+	delivery := make(chan kafka.Event, 1)
+	if err := p.Produce(msg, delivery); err != nil {
+		return err
+	}
+
+	m, ok := (<-delivery).(*kafka.Message)
+	if !ok {
+		return errors.New("kafka: unexpected delivery event")
+	}
+	return m.TopicPartition.Error
+}
+
+// attemptsOf returns the failed attempts recorded on a retried message.
+func attemptsOf(md Metadata) int {
+	n, _ := strconv.Atoi(md.Headers[HeaderAttempt]) //nolint:errcheck
+	return n
+}
+
+// ReplayConfig holds the configuration for ReplayDLQ.
+type ReplayConfig struct {
+	Brokers []string
+	// Topic is the source topic whose DLQ is replayed.
+	Topic string
+	// GroupID tracks replay progress, default "<dlq topic>.replay".
+	GroupID         string
+	ConsumerOptions map[string]any
+	ProducerOptions map[string]any
+}
+
+// ReplayDLQ moves every message currently in the DLQ of cfg.Topic back to the
+// topic it originally failed on, with the retry headers removed. Messages that
+// arrive in the DLQ after the replay starts are left for the next run. It returns
+// the number of replayed messages.
+func ReplayDLQ(ctx context.Context, cfg ReplayConfig, log *logrus.Logger) (int, error) {
+	dlq := DLQTopic(cfg.Topic)
+	if cfg.GroupID == "" {
+		cfg.GroupID = dlq + ".replay"
+	}
+
+	options := map[string]any{
+		"enable.auto.commit": false,
+		"auto.offset.reset":  "earliest",
+	}
+	for k, v := range cfg.ConsumerOptions {
+		options[k] = v
+	}
+	c, err := NewKafkaConsumer(ConsumerConfig{Brokers: cfg.Brokers, GroupID: cfg.GroupID, Options: options})
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = c.Close() //nolint:errcheck
+	}()
+
+	pm := &kafka.ConfigMap{"bootstrap.servers": strings.Join(cfg.Brokers, ",")}
+	for k, v := range cfg.ProducerOptions {
+		_ = pm.SetKey(k, v) //nolint:errcheck
+	}
+	p, err := kafka.NewProducer(pm)
+	if err != nil {
+		return 0, err
+	}
+	defer p.Close()
+
+	// Replay up to the high watermark of each partition as of now.
+	meta, err := c.GetMetadata(&dlq, false, 10000)
+	if err != nil {
+		return 0, err
+	}
+	end := make(map[int32]int64)
+	var assign []kafka.TopicPartition
+	for _, part := range meta.Topics[dlq].Partitions {
+		_, high, err := c.QueryWatermarkOffsets(dlq, part.ID, 10000)
+		if err != nil {
+			return 0, err
+		}
+		end[part.ID] = high
+		assign = append(assign, kafka.TopicPartition{Topic: &dlq, Partition: part.ID, Offset: kafka.OffsetStored})
+	}
+	if err := c.Assign(assign); err != nil {
+		return 0, err
+	}
+
+	// Skip partitions whose committed position is already at the end.
+	positions, err := c.Committed(assign, 10000)
+	if err != nil {
+		return 0, err
+	}
+	for _, tp := range positions {
+		if tp.Offset >= 0 && int64(tp.Offset) >= end[tp.Partition] {
+			delete(end, tp.Partition)
+		}
+	}
+	for id, high := range end {
+		if high == 0 {
+			delete(end, id)
+		}
+	}
+
+	replayed := 0
+	for len(end) > 0 {
+		if err := ctx.Err(); err != nil {
+			return replayed, err
+		}
+
+		msg, err := c.ReadMessage(100 * time.Millisecond)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.IsTimeout() {
+				continue
+			}
+			return replayed, err
+		}
+
+		target := cfg.Topic
+		headers := make([]kafka.Header, 0, len(msg.Headers))
+		for _, h := range msg.Headers {
+			switch h.Key {
+			case HeaderOriginalTopic:
+				target = string(h.Value)
+			case HeaderOriginalPartition, HeaderOriginalOffset, HeaderError, HeaderAttempt:
+			default:
+				headers = append(headers, h)
+			}
+		}
+
+		err = produceSync(p, &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &target, Partition: kafka.PartitionAny},
+			Key:            msg.Key,
+			Value:          msg.Value,
+			Headers:        headers,
+		})
+		if err != nil {
+			return replayed, fmt.Errorf("kafka: replay offset %d: %w", msg.TopicPartition.Offset, err)
+		}
+		if _, err := c.CommitMessage(msg); err != nil {
+			return replayed, err
+		}
+		replayed++
+
+		if int64(msg.TopicPartition.Offset)+1 >= end[msg.TopicPartition.Partition] {
+			delete(end, msg.TopicPartition.Partition)
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"topic":    cfg.Topic,
+		"replayed": replayed,
+		"module":   "Kafka",
+	}).Info("Kafka DLQ replay finished")
+
+	return replayed, nil
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 09.05
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRetryTopic(t *testing.T) {
+	tests := map[time.Duration]string{
+		time.Minute:            "orders.retry.1m",
+		10 * time.Minute:       "orders.retry.10m",
+		2 * time.Hour:          "orders.retry.2h",
+		30 * time.Second:       "orders.retry.30s",
+		250 * time.Millisecond: "orders.retry.250ms",
+	}
+
+	for delay, want := range tests {
+		if got := RetryTopic("orders", delay); got != want {
+			t.Errorf("RetryTopic(%v): expected %s, got %s", delay, want, got)
+		}
+	}
+	if got := DLQTopic("orders"); got != "orders.dlq" {
+		t.Errorf("Expected orders.dlq, got %s", got)
+	}
+}
+
+func TestConsumer_RetryThenDLQThenReplay(t *testing.T) {
+	delay := 200 * time.Millisecond
+	mc := newMockCluster(t, "devices", RetryTopic("devices", delay), DLQTopic("devices"))
+	brokers := mc.BootstrapServers()
+	produceEvents(t, brokers, "devices",
+		deviceEvent{ID: "1", JID: "a"},
+		deviceEvent{ID: "2", JID: "b"},
+	)
+
+	var mu sync.Mutex
+	var attempts []Metadata
+	var handled []string
+	healthy := false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	handler := HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if evt.ID == "2" && !healthy {
+			attempts = append(attempts, md)
+			if len(attempts) == 2 {
+				// Give the consumer time to dead-letter the message before stopping.
+				time.AfterFunc(500*time.Millisecond, cancel)
+			}
+			return errors.New("downstream unavailable")
+		}
+		handled = append(handled, evt.ID)
+		if healthy {
+			cancel()
+		}
+		return nil
+	})
+
+	cfg := ConsumerConfig{
+		Brokers: []string{brokers},
+		GroupID: "test",
+		Topics:  []string{"devices"},
+		// The mock cluster waits a full session timeout before a group rejoins.
+		Options: map[string]any{"auto.offset.reset": "earliest", "session.timeout.ms": 6000},
+		Retry:   &RetryPolicy{Delays: []time.Duration{delay}},
+	}
+	c, err := NewConsumer(cfg, handler, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cancel()
+
+	if len(attempts) != 2 {
+		t.Fatalf("Expected 2 failed attempts, got %d", len(attempts))
+	}
+	retried := attempts[1]
+	if retried.Topic != RetryTopic("devices", delay) || retried.Attempt != 2 {
+		t.Errorf("Expected second attempt on retry topic, got %s attempt %d", retried.Topic, retried.Attempt)
+	}
+	if retried.Headers[HeaderOriginalTopic] != "devices" || retried.Headers[HeaderOriginalOffset] != "1" {
+		t.Errorf("Expected original topic and offset headers, got %v", retried.Headers)
+	}
+	if elapsed := retried.Timestamp.Sub(attempts[0].Timestamp); elapsed < 0 {
+		t.Errorf("Expected retry to be produced after the original, got %v", elapsed)
+	}
+
+	// The second failure went to the DLQ; replay it once the handler is healthy.
+	n, err := ReplayDLQ(context.Background(), ReplayConfig{Brokers: []string{brokers}, Topic: "devices"}, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 replayed message, got %d", n)
+	}
+
+	healthy = true
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c, err = NewConsumer(cfg, handler, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(handled) != 2 || handled[0] != "1" || handled[1] != "2" {
+		t.Errorf("Expected 1 then the replayed 2, got %v", handled)
+	}
+
+	if n, err := ReplayDLQ(context.Background(), ReplayConfig{Brokers: []string{brokers}, Topic: "devices"}, logrus.New()); err != nil || n != 0 {
+		t.Errorf("Expected nothing left to replay, got %d (%v)", n, err)
+	}
+}
+
+func TestConsumer_ForwardFailureRedelivers(t *testing.T) {
+	delays := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second}
+
+	tests := []struct {
+		name   string
+		value  string
+		target string
+	}{
+		// Attempt 2 to 4 fail to reach their retry topic; attempt 4 is forwarded.
+		{"handler error", `{"id":"1","jid":"a"}`, RetryTopic("devices", 4*time.Second)},
+		// Undecodable messages go to the DLQ; the copy is written on attempt 4.
+		{"undecodable", "{not json", DLQTopic("devices")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topics := []string{"devices", DLQTopic("devices")}
+			for _, d := range delays {
+				topics = append(topics, RetryTopic("devices", d))
+			}
+			mc := newMockCluster(t, topics...)
+			brokers := mc.BootstrapServers()
+
+			// The message was replayed from the DLQ after one failed attempt.
+			p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers, "go.delivery.reports": false})
+			if err != nil {
+				t.Fatalf("Failed to create producer: %v", err)
+			}
+			topic := "devices"
+			_ = p.Produce(&kafka.Message{ //nolint:errcheck
+				TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0},
+				Value:          []byte(tt.value),
+				Headers:        []kafka.Header{{Key: HeaderAttempt, Value: []byte("1")}},
+			}, nil)
+			p.Flush(5000)
+			p.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			var attempts []int
+			c, err := NewConsumer(ConsumerConfig{
+				Brokers: []string{brokers},
+				GroupID: "test",
+				Topics:  []string{"devices"},
+				Backoff: backoff.Policy{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond},
+				Options: map[string]any{"auto.offset.reset": "earliest"},
+				Retry:   &RetryPolicy{Delays: delays},
+			}, HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+				attempts = append(attempts, md.Attempt)
+				return errors.New("downstream unavailable")
+			}), logrus.New())
+			if err != nil {
+				t.Fatalf("Failed to create consumer: %v", err)
+			}
+
+			var forwards []string
+			send := c.retry.send
+			c.retry.send = func(msg *kafka.Message) error {
+				forwards = append(forwards, *msg.TopicPartition.Topic)
+				if len(forwards) <= 2 {
+					return errors.New("broker unavailable")
+				}
+				defer cancel()
+				return send(msg)
+			}
+
+			if err := c.Run(ctx); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(forwards) != 3 || forwards[2] != tt.target {
+				t.Fatalf("Expected the third forward to %s, got %v", tt.target, forwards)
+			}
+			if md := readMessage(t, brokers, tt.target, 0); md.Headers[HeaderAttempt] != "4" {
+				t.Errorf("Expected the forwarded copy to record attempt 4, got %q", md.Headers[HeaderAttempt])
+			}
+			if tt.target != DLQTopic("devices") && !slices.Equal(attempts, []int{2, 3, 4}) {
+				t.Errorf("Expected attempts 2, 3 and 4, got %v", attempts)
+			}
+			if off := committedOffset(t, brokers, "test", "devices"); off != 1 {
+				t.Errorf("Expected committed offset 1 after forwarding, got %v", off)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 14.10
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// abortTimeout bounds aborting a transaction whose context has ended.
+	abortTimeout = 30 * time.Second
+	// commitTimeout bounds retrying the commit of a transaction.
+	commitTimeout = 30 * time.Second
+)
+
+// commitBackoff spaces out retries of a transaction commit.
+var commitBackoff = backoff.Policy{Initial: 100 * time.Millisecond, Max: 5 * time.Second, Jitter: 0.2}
+
+// NewTransactionalProducer creates a KafkaProducer with transactional.id set to
+// transactionalID and initializes its transactions, fencing off any previous
+// producer with the same ID. Use a stable ID per consumer instance, e.g. derived
+// from the pod name. Like any producer it needs StartProducerPollLoop.
+func NewTransactionalProducer(ctx context.Context, cfg *kafka.ConfigMap, transactionalID string, log *logrus.Logger) (*KafkaProducer, error) {
+	if transactionalID == "" {
+		return nil, errors.New("kafka: transactional producer needs a transactional.id")
+	}
+	if err := cfg.SetKey("transactional.id", transactionalID); err != nil {
+		return nil, err
+	}
+
+	p, err := kafka.NewProducer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.InitTransactions(ctx); err != nil {
+		p.Close()
+		return nil, err
+	}
+
+	return &KafkaProducer{
+		p:   p,
+		log: log,
+	}, nil
+}
+
+func (k *KafkaProducer) BeginTransaction() error {
+	return k.p.BeginTransaction()
+}
+
+// CommitTransaction flushes the messages sent in the current transaction and
+// commits them. A retriable error is retried with backoff for up to 30s, or
+// until ctx ends; the transaction is then aborted and the error returned. An
+// error that requires it aborts the transaction straight away.
+func (k *KafkaProducer) CommitTransaction(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, commitTimeout)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		err := k.p.CommitTransaction(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var kerr kafka.Error
+		if !errors.As(err, &kerr) {
+			return err
+		}
+		if kerr.IsRetriable() {
+			if backoff.Sleep(ctx, commitBackoff.Delay(attempt)) == nil {
+				continue
+			}
+			return errors.Join(err, k.AbortTransaction(ctx))
+		}
+		if kerr.TxnRequiresAbort() {
+			return errors.Join(err, k.AbortTransaction(ctx))
+		}
+		return err
+	}
+}
+
+// AbortTransaction drops the messages sent in the current transaction. It still
+// runs, for up to 30s, when ctx has already ended.
+func (k *KafkaProducer) AbortTransaction(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortTimeout)
+	defer cancel()
+	return k.p.AbortTransaction(ctx)
+}
+
+// SendOffsetsToTransaction commits offsets for the consumer group of group with
+// the current transaction. Offsets are those of the next messages to consume.
+func (k *KafkaProducer) SendOffsetsToTransaction(ctx context.Context, offsets []kafka.TopicPartition, group *kafka.ConsumerGroupMetadata) error {
+	return k.p.SendOffsetsToTransaction(ctx, offsets, group)
+}
+
+// WithTransaction runs fn in a transaction: the messages it sends through k are
+// committed when it returns nil and aborted when it returns an error.
+func (k *KafkaProducer) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := k.BeginTransaction(); err != nil {
+		return err
+	}
+
+	if err := fn(ctx); err != nil {
+		if aerr := k.AbortTransaction(ctx); aerr != nil {
+			k.log.WithError(aerr).WithField("module", "Kafka").Error("failed to abort kafka transaction")
+			return errors.Join(err, aerr)
+		}
+		return err
+	}
+	return k.CommitTransaction(ctx)
+}
+
+// transact handles msg in a transaction of cfg.Transactions that also commits
+// its offset, so the events the handler sends and the offset are committed
+// together.
+func (c *Consumer[T]) transact(ctx context.Context, msg *kafka.Message, handle func(ctx context.Context) error) error {
+	return c.cfg.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		if handle != nil {
+			if err := handle(ctx); err != nil {
+				return err
+			}
+		}
+
+		group, err := c.c.GetConsumerGroupMetadata()
+		if err != nil {
+			return err
+		}
+		tp := msg.TopicPartition
+		tp.Offset++
+		return c.cfg.Transactions.SendOffsetsToTransaction(ctx, []kafka.TopicPartition{tp}, group)
+	})
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 15.00
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/kafka
+ */
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/producer"
+	"github.com/PakaiWA/pakaiwa-platform/runtime/backoff"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/sirupsen/logrus"
+)
+
+func newTransactionalProducer(t *testing.T, ctx context.Context, brokers string) *KafkaProducer {
+	t.Helper()
+
+	p, err := NewTransactionalProducer(ctx, &kafka.ConfigMap{"bootstrap.servers": brokers}, "aggregator-0", logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create transactional producer: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = p.Close() //nolint:errcheck
+	})
+	StartProducerPollLoop(ctx, p, logrus.New())
+	return p
+}
+
+// readCommitted returns the committed messages of partition 0 of topic.
+func readCommitted(t *testing.T, brokers, topic string) []deviceEvent {
+	t.Helper()
+
+	c, err := NewKafkaConsumer(ConsumerConfig{
+		Brokers: []string{brokers},
+		GroupID: "reader",
+		Options: map[string]any{"isolation.level": "read_committed", "enable.partition.eof": true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer func() {
+		_ = c.Close() //nolint:errcheck
+	}()
+
+	if err := c.Assign([]kafka.TopicPartition{{Topic: &topic, Partition: 0, Offset: kafka.OffsetBeginning}}); err != nil {
+		t.Fatalf("Failed to assign: %v", err)
+	}
+
+	var events []deviceEvent
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		switch e := c.Poll(100).(type) {
+		case *kafka.Message:
+			var evt deviceEvent
+			if err := json.Unmarshal(e.Value, &evt); err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			events = append(events, evt)
+		case kafka.PartitionEOF:
+			return events
+		}
+	}
+	t.Fatal("Timed out reading committed messages")
+	return nil
+}
+
+func TestKafkaProducer_WithTransaction(t *testing.T) {
+	mc := newMockCluster(t, "aggregates")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := newTransactionalProducer(t, ctx, mc.BootstrapServers())
+
+	send := func(ctx context.Context, id string) error {
+		msg, err := producer.EventMessage("aggregates", deviceEvent{ID: id, JID: "a"})
+		if err != nil {
+			return err
+		}
+		return p.Send(ctx, msg)
+	}
+
+	errFailed := errors.New("aggregation failed")
+	err := p.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := send(ctx, "aborted"); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Expected the function's error, got %v", err)
+	}
+
+	if err := p.WithTransaction(ctx, func(ctx context.Context) error { return send(ctx, "committed") }); err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	events := readCommitted(t, mc.BootstrapServers(), "aggregates")
+	if len(events) != 1 || events[0].ID != "committed" {
+		t.Errorf("Expected only the committed event, got %v", events)
+	}
+}
+
+func TestConsumer_Transactions(t *testing.T) {
+	mc := newMockCluster(t, "receipts", "aggregates")
+	brokers := mc.BootstrapServers()
+	produceEvents(t, brokers, "receipts", deviceEvent{ID: "1", JID: "a"})
+
+	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": brokers, "go.delivery.reports": false})
+	if err != nil {
+		t.Fatalf("Failed to create producer: %v", err)
+	}
+	topic := "receipts"
+	_ = p.Produce(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0}, Value: []byte("{not json")}, nil) //nolint:errcheck
+	p.Flush(5000)
+	p.Close()
+
+	produceEvents(t, brokers, "receipts", deviceEvent{ID: "2", JID: "a"}, deviceEvent{ID: "3", JID: "a"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	txp := newTransactionalProducer(t, ctx, brokers)
+	out := &Producer[deviceEvent]{Producer: txp, Topic: "aggregates", Log: logrus.New()}
+
+	failed := false
+	handled := 0
+	handler := HandlerFunc[deviceEvent](func(ctx context.Context, evt deviceEvent, md Metadata) error {
+		if err := out.Send(ctx, deviceEvent{ID: "out-" + evt.ID, JID: evt.JID}, ""); err != nil {
+			return err
+		}
+		if evt.ID == "2" && !failed {
+			failed = true
+			return errors.New("temporary failure")
+		}
+		if handled++; handled == 3 {
+			cancel()
+		}
+		return nil
+	})
+
+	if _, err := NewConsumer(ConsumerConfig{Topics: []string{"receipts"}, Transactions: txp, Concurrency: 4}, handler, logrus.New()); err == nil {
+		t.Error("Expected transactions with concurrency to be rejected")
+	}
+
+	c, err := NewConsumer(ConsumerConfig{
+		Brokers:      []string{brokers},
+		GroupID:      "aggregator",
+		Topics:       []string{"receipts"},
+		Options:      map[string]any{"auto.offset.reset": "earliest"},
+		Backoff:      backoff.Policy{Initial: 10 * time.Millisecond, Max: 10 * time.Millisecond},
+		Transactions: txp,
+	}, handler, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	events := readCommitted(t, brokers, "aggregates")
+	var ids []string
+	for _, evt := range events {
+		ids = append(ids, evt.ID)
+	}
+	if len(ids) != 3 || ids[0] != "out-1" || ids[1] != "out-2" || ids[2] != "out-3" {
+		t.Errorf("Expected each output exactly once, got %v", ids)
+	}
+	// The mock broker acknowledges transactional offset commits without storing
+	// them, so any offset found here was committed outside a transaction.
+	if off := committedOffset(t, brokers, "aggregator", "receipts"); off != kafka.OffsetInvalid {
+		t.Errorf("Expected offsets to be committed only in transactions, got %v", off)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 20.35
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/producer
+ */
+
+package producer
+
+import (
+	"encoding/json"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
+)
+
+// CloudEvents headers as named by the Kafka protocol binding. The HTTP producer
+// sends them as ce- headers and Content-Type.
+const (
+	CloudEventsHeaderPrefix  = "ce_"
+	HeaderCloudEventsContent = "content-type"
+)
+
+// CloudEventMessage wraps evt in a CloudEvent from source and encodes it for
+// topic in the content mode of cfg. The message is keyed by evt.EventKey and
+// carries the event_name and content_type headers of EventMessage as well, so
+// consumers that do not speak CloudEvents can still route and decode it.
+func CloudEventMessage(topic string, evt event.Event, cfg event.CloudEventConfig) (Message, error) {
+	ce, err := event.NewCloudEvent(cfg.Source, evt)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{
+		Topic:   topic,
+		Key:     []byte(evt.EventKey()),
+		Headers: map[string]string{HeaderEventName: ce.Type},
+	}
+
+	switch cfg.Mode {
+	case event.StructuredMode:
+		if msg.Value, err = json.Marshal(ce); err != nil {
+			return Message{}, err
+		}
+		msg.Headers[HeaderCloudEventsContent] = event.ContentTypeCloudEvents
+		msg.Headers[HeaderContentType] = event.ContentTypeCloudEvents
+	default:
+		msg.Value = ce.Data
+		for k, v := range ce.Attributes() {
+			msg.Headers[CloudEventsHeaderPrefix+k] = v
+		}
+		msg.Headers[HeaderCloudEventsContent] = ce.DataContentType
+		msg.Headers[HeaderContentType] = ce.DataContentType
+	}
+	return msg, nil
+}
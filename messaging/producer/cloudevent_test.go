@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 20.50
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/producer
+ */
+
+package producer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
+)
+
+type sessionEvent struct {
+	ID  string    `json:"id"`
+	JID string    `json:"jid"`
+	At  time.Time `json:"at"`
+}
+
+func (e sessionEvent) EventID() string      { return e.ID }
+func (e sessionEvent) EventName() string    { return "session.opened" }
+func (e sessionEvent) EventKey() string     { return e.JID }
+func (e sessionEvent) EventSubject() string { return "device/" + e.JID }
+func (e sessionEvent) EventTime() time.Time { return e.At }
+
+func TestCloudEventMessage_Binary(t *testing.T) {
+	at := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	evt := sessionEvent{ID: "1", JID: "a", At: at}
+
+	msg, err := CloudEventMessage("sessions", evt, event.CloudEventConfig{Source: "/pakaiwa/api"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"ce_specversion":         "1.0",
+		"ce_id":                  "1",
+		"ce_source":              "/pakaiwa/api",
+		"ce_type":                "session.opened",
+		"ce_subject":             "device/a",
+		"ce_time":                "2026-10-19T08:00:00Z",
+		HeaderCloudEventsContent: "application/json",
+		HeaderContentType:        "application/json",
+		HeaderEventName:          "session.opened",
+	}
+	if len(msg.Headers) != len(want) {
+		t.Errorf("Expected headers %v, got %v", want, msg.Headers)
+	}
+	for k, v := range want {
+		if msg.Headers[k] != v {
+			t.Errorf("Expected header %s %q, got %q", k, v, msg.Headers[k])
+		}
+	}
+
+	var data sessionEvent
+	if err := json.Unmarshal(msg.Value, &data); err != nil || data != evt {
+		t.Errorf("Expected the event as value, got %s (%v)", msg.Value, err)
+	}
+	if msg.Topic != "sessions" || string(msg.Key) != "a" {
+		t.Errorf("Expected topic sessions and key a, got %s/%s", msg.Topic, msg.Key)
+	}
+}
+
+func TestCloudEventMessage_Structured(t *testing.T) {
+	evt := sessionEvent{ID: "1", JID: "a", At: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+
+	msg, err := CloudEventMessage("sessions", evt, event.CloudEventConfig{Source: "/pakaiwa/api", Mode: event.StructuredMode})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(msg.Headers) != 3 || msg.Headers[HeaderCloudEventsContent] != event.ContentTypeCloudEvents ||
+		msg.Headers[HeaderContentType] != event.ContentTypeCloudEvents || msg.Headers[HeaderEventName] != "session.opened" {
+		t.Errorf("Expected the cloudevents content type and event name headers, got %v", msg.Headers)
+	}
+
+	var ce event.CloudEvent
+	if err := json.Unmarshal(msg.Value, &ce); err != nil {
+		t.Fatalf("Failed to decode envelope: %v", err)
+	}
+	if ce.SpecVersion != "1.0" || ce.ID != "1" || ce.Type != "session.opened" || ce.Subject != "device/a" || !ce.Time.Equal(evt.At) {
+		t.Errorf("Unexpected envelope %+v", ce)
+	}
+
+	var data sessionEvent
+	if err := json.Unmarshal(ce.Data, &data); err != nil || data != evt {
+		t.Errorf("Expected the event as data, got %s (%v)", ce.Data, err)
+	}
+}
+
+func TestCloudEventMessage_RequiresSource(t *testing.T) {
+	if _, err := CloudEventMessage("sessions", sessionEvent{ID: "1"}, event.CloudEventConfig{}); err == nil {
+		t.Error("Expected error without a source")
+	}
+}
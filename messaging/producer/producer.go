@@ -17,10 +17,96 @@ package producer
 
 import (
 	"context"
+	"maps"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/codec"
+	"github.com/PakaiWA/pakaiwa-platform/messaging/event"
+	"github.com/PakaiWA/pakaiwa-platform/observability/logging/ctxmeta"
+)
+
+// Header names set on produced messages.
+const (
+	HeaderEventName   = "event_name"
+	HeaderContentType = "content_type"
+	HeaderTraceID     = "trace_id"
+	HeaderDeviceID    = "device_id"
+	// HeaderContentEncoding is set when the value is compressed.
+	HeaderContentEncoding = "content_encoding"
 )
 
+const ContentTypeJSON = codec.ContentTypeJSON
+
+// Message is a message to send. Each producer maps it onto its own transport.
+type Message struct {
+	Topic     string
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Timestamp time.Time // zero lets the producer or broker set it
+	Partition *int32    // partition hint, nil lets the producer choose
+}
+
 type MessageProducer interface {
-	Send(ctx context.Context, topic string, key []byte, clientJID []byte, value []byte) error
+	Send(ctx context.Context, msg Message) error
 	Flush(timeoutMs int) int
 	Close() error
 }
+
+// SyncSender is implemented by producers that can wait for a message to be
+// acknowledged, such as the Kafka producer. It returns the partition and offset
+// the message was written to.
+type SyncSender interface {
+	SendSync(ctx context.Context, msg Message) (int32, int64, error)
+}
+
+// EventMessage marshals evt as JSON into a message for topic, keyed by
+// evt.EventKey and carrying its event name and content type as headers.
+func EventMessage(topic string, evt event.Event) (Message, error) {
+	return EncodeMessage(topic, evt, codec.JSON{}, nil)
+}
+
+// EncodeMessage is EventMessage with codec c instead of JSON. When compression
+// is set, large values are compressed and carry the content_encoding header.
+func EncodeMessage(topic string, evt event.Event, c codec.Codec, compression *codec.Compression) (Message, error) {
+	value, err := c.Marshal(evt)
+	if err != nil {
+		return Message{}, err
+	}
+
+	headers := map[string]string{
+		HeaderEventName:   evt.EventName(),
+		HeaderContentType: c.ContentType(),
+	}
+	if compression != nil {
+		var encoding string
+		if value, encoding, err = compression.Compress(value); err != nil {
+			return Message{}, err
+		}
+		if encoding != "" {
+			headers[HeaderContentEncoding] = encoding
+		}
+	}
+
+	return Message{
+		Topic:   topic,
+		Key:     []byte(evt.EventKey()),
+		Value:   value,
+		Headers: headers,
+	}, nil
+}
+
+// Headers returns the headers to send with msg: its own, plus the trace ID of ctx
+// unless msg already sets one. msg.Headers is not modified.
+func Headers(ctx context.Context, msg Message) map[string]string {
+	headers := maps.Clone(msg.Headers)
+	if headers == nil {
+		headers = make(map[string]string, 1)
+	}
+	if _, ok := headers[HeaderTraceID]; !ok {
+		if traceID := ctxmeta.TraceID(ctx); traceID != "" {
+			headers[HeaderTraceID] = traceID
+		}
+	}
+	return headers
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 09.40
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/schema
+ */
+
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const contentTypeRegistry = "application/vnd.schemaregistry.v1+json"
+
+// ClientConfig configures a Client.
+type ClientConfig struct {
+	URL      string
+	Username string // basic auth, e.g. a Confluent Cloud API key
+	Password string
+	Timeout  time.Duration // default 10s
+}
+
+// Client is a Registry backed by the REST API of a Confluent-compatible schema
+// registry. Schemas by ID are cached, since they never change.
+type Client struct {
+	cfg  ClientConfig
+	http *http.Client
+
+	mu   sync.RWMutex
+	byID map[int]Schema
+}
+
+func NewClient(cfg ClientConfig) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+
+	return &Client{
+		cfg:  cfg,
+		http: &http.Client{Timeout: cfg.Timeout},
+		byID: make(map[int]Schema),
+	}
+}
+
+// registryError is the error body of the schema registry API.
+type registryError struct {
+	Status  int    `json:"-"`
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (e *registryError) Error() string {
+	return fmt.Sprintf("schema: registry error %d: %s", e.Code, e.Message)
+}
+
+func (e *registryError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrIncompatible:
+		return e.Status == http.StatusConflict
+	}
+	return false
+}
+
+func (c *Client) Register(ctx context.Context, subject string, s Schema) (int, error) {
+	var res struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", request(s), &res); err != nil {
+		return 0, err
+	}
+	return res.ID, nil
+}
+
+func (c *Client) Lookup(ctx context.Context, subject string, s Schema) (Schema, error) {
+	var res Schema
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject), request(s), &res); err != nil {
+		return Schema{}, err
+	}
+	return withDefaults(res), nil
+}
+
+func (c *Client) Latest(ctx context.Context, subject string) (Schema, error) {
+	var res Schema
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, &res); err != nil {
+		return Schema{}, err
+	}
+	return withDefaults(res), nil
+}
+
+func (c *Client) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	c.mu.RLock()
+	s, ok := c.byID[id]
+	c.mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &s); err != nil {
+		return Schema{}, err
+	}
+	s = withDefaults(s)
+	s.ID = id
+
+	c.mu.Lock()
+	c.byID[id] = s
+	c.mu.Unlock()
+	return s, nil
+}
+
+func (c *Client) Compatible(ctx context.Context, subject string, s Schema) (bool, error) {
+	var res struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	err := c.do(ctx, http.MethodPost, "/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest", request(s), &res)
+	if errors.Is(err, ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return res.IsCompatible, nil
+}
+
+// request is the body of the register, lookup and compatibility endpoints.
+func request(s Schema) Schema {
+	return Schema{Type: s.Type, Schema: s.Schema}
+}
+
+// withDefaults sets the schemaType the registry omits for its default type.
+func withDefaults(s Schema) Schema {
+	if s.Type == "" {
+		s.Type = "AVRO"
+	}
+	return s
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.URL+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentTypeRegistry)
+	if body != nil {
+		req.Header.Set("Content-Type", contentTypeRegistry)
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode >= 300 {
+		rerr := &registryError{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(rerr); err != nil {
+			rerr.Code, rerr.Message = resp.StatusCode, resp.Status
+		}
+		return rerr
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 12.05
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/schema
+ */
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// registryServer serves the schema registry API from a Memory.
+func registryServer(t *testing.T, m *Memory) *httptest.Server {
+	t.Helper()
+
+	reply := func(w http.ResponseWriter, v any, err error) {
+		w.Header().Set("Content-Type", contentTypeRegistry)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			v = map[string]any{"error_code": 40401, "message": "Subject not found"}
+		case errors.Is(err, ErrIncompatible):
+			w.WriteHeader(http.StatusConflict)
+			v = map[string]any{"error_code": 409, "message": "Schema being registered is incompatible"}
+		}
+		_ = json.NewEncoder(w).Encode(v) //nolint:errcheck
+	}
+	body := func(r *http.Request) Schema {
+		var s Schema
+		_ = json.NewDecoder(r.Body).Decode(&s) //nolint:errcheck
+		return s
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /subjects/{subject}/versions", func(w http.ResponseWriter, r *http.Request) {
+		id, err := m.Register(r.Context(), r.PathValue("subject"), body(r))
+		reply(w, map[string]int{"id": id}, err)
+	})
+	mux.HandleFunc("POST /subjects/{subject}", func(w http.ResponseWriter, r *http.Request) {
+		s, err := m.Lookup(r.Context(), r.PathValue("subject"), body(r))
+		reply(w, s, err)
+	})
+	mux.HandleFunc("GET /subjects/{subject}/versions/latest", func(w http.ResponseWriter, r *http.Request) {
+		s, err := m.Latest(r.Context(), r.PathValue("subject"))
+		reply(w, s, err)
+	})
+	mux.HandleFunc("GET /schemas/ids/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id")) //nolint:errcheck
+		s, err := m.SchemaByID(r.Context(), id)
+		reply(w, Schema{Type: s.Type, Schema: s.Schema}, err)
+	})
+	mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/latest", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := m.Latest(r.Context(), r.PathValue("subject")); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		ok, err := m.Compatible(r.Context(), r.PathValue("subject"), body(r))
+		reply(w, map[string]bool{"is_compatible": ok}, err)
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "key" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error_code":401,"message":"Unauthorized"}`)) //nolint:errcheck
+			return
+		}
+		if r.Method == http.MethodPost && !strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeRegistry) {
+			t.Errorf("Expected registry content type, got %q", r.Header.Get("Content-Type"))
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	srv := registryServer(t, NewMemory())
+	c := NewClient(ClientConfig{URL: srv.URL + "/", Username: "key", Password: "secret"})
+
+	if ok, err := c.Compatible(ctx, "devices-value", Schema{Type: TypeJSON, Schema: deviceSchema}); err != nil || !ok {
+		t.Errorf("Expected a new subject to be compatible, got %v (%v)", ok, err)
+	}
+	id, err := c.Register(ctx, "devices-value", Schema{Type: TypeJSON, Schema: deviceSchema})
+	if err != nil || id != 1 {
+		t.Fatalf("Expected schema 1, got %d (%v)", id, err)
+	}
+
+	latest, err := c.Latest(ctx, "devices-value")
+	if err != nil || latest.ID != id || latest.Version != 1 || latest.Type != TypeJSON {
+		t.Errorf("Expected version 1 of schema %d, got %+v (%v)", id, latest, err)
+	}
+	if s, err := c.Lookup(ctx, "devices-value", Schema{Type: TypeJSON, Schema: deviceSchema}); err != nil || s.ID != id {
+		t.Errorf("Expected lookup to find schema %d, got %+v (%v)", id, s, err)
+	}
+	if s, err := c.SchemaByID(ctx, id); err != nil || s.ID != id || s.Schema != deviceSchema {
+		t.Errorf("Expected schema %d by id, got %+v (%v)", id, s, err)
+	}
+
+	renamed := Schema{Type: TypeJSON, Schema: `{"type":"object","properties":{"device_jid":{"type":"string"}},"required":["device_jid"]}`}
+	if ok, err := c.Compatible(ctx, "devices-value", renamed); err != nil || ok {
+		t.Errorf("Expected renamed property to be incompatible, got %v (%v)", ok, err)
+	}
+	if _, err := c.Register(ctx, "devices-value", renamed); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible, got %v", err)
+	}
+	if _, err := c.Latest(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	unauthorized := NewClient(ClientConfig{URL: srv.URL})
+	if _, err := unauthorized.Latest(ctx, "devices-value"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,287 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 10.25
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/schema
+ */
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Memory is an in-process Registry for local development and tests. It checks
+// backward compatibility of JSON schemas only; Protobuf schemas are accepted
+// as they are. A Memory opened with OpenFile saves every registered schema to
+// its file.
+type Memory struct {
+	mu       sync.RWMutex
+	subjects map[string][]Schema
+	byID     map[int]Schema
+	path     string
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		subjects: make(map[string][]Schema),
+		byID:     make(map[int]Schema),
+	}
+}
+
+// OpenFile returns a Memory backed by the JSON file at path, which is created on
+// the first Register if it does not exist.
+func OpenFile(path string) (*Memory, error) {
+	m := NewMemory()
+	m.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var schemas []Schema
+	if err := json.Unmarshal(data, &schemas); err != nil {
+		return nil, fmt.Errorf("schema: reading %s: %w", path, err)
+	}
+	for _, s := range schemas {
+		m.subjects[s.Subject] = append(m.subjects[s.Subject], s)
+		m.byID[s.ID] = Schema{ID: s.ID, Type: s.Type, Schema: s.Schema}
+	}
+	return m, nil
+}
+
+func (m *Memory) Register(_ context.Context, subject string, s Schema) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s = normalize(s)
+	versions := m.subjects[subject]
+	for _, v := range versions {
+		if same(v, s) {
+			return v.ID, nil
+		}
+	}
+	if len(versions) > 0 {
+		if err := compatible(versions[len(versions)-1], s); err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrIncompatible, err)
+		}
+	}
+
+	s.ID = m.idOf(s)
+	s.Subject = subject
+	s.Version = len(versions) + 1
+	_, known := m.byID[s.ID]
+	m.subjects[subject] = append(versions, s)
+	m.byID[s.ID] = Schema{ID: s.ID, Type: s.Type, Schema: s.Schema}
+
+	if err := m.save(); err != nil {
+		m.subjects[subject] = versions
+		if !known {
+			delete(m.byID, s.ID)
+		}
+		return 0, err
+	}
+	return s.ID, nil
+}
+
+// idOf returns the ID of a schema already registered under another subject, or
+// the next free ID.
+func (m *Memory) idOf(s Schema) int {
+	next := 1
+	for id, v := range m.byID {
+		if same(v, s) {
+			return id
+		}
+		next = max(next, id+1)
+	}
+	return next
+}
+
+func (m *Memory) Lookup(_ context.Context, subject string, s Schema) (Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s = normalize(s)
+	for _, v := range m.subjects[subject] {
+		if same(v, s) {
+			return v, nil
+		}
+	}
+	return Schema{}, ErrNotFound
+}
+
+func (m *Memory) Latest(_ context.Context, subject string) (Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	versions := m.subjects[subject]
+	if len(versions) == 0 {
+		return Schema{}, ErrNotFound
+	}
+	return versions[len(versions)-1], nil
+}
+
+func (m *Memory) SchemaByID(_ context.Context, id int) (Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.byID[id]
+	if !ok {
+		return Schema{}, ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *Memory) Compatible(_ context.Context, subject string, s Schema) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	versions := m.subjects[subject]
+	if len(versions) == 0 {
+		return true, nil
+	}
+	return compatible(versions[len(versions)-1], normalize(s)) == nil, nil
+}
+
+// save writes every version to the file of m, if it has one, through a
+// temporary file so a crash never leaves it half written.
+func (m *Memory) save() error {
+	if m.path == "" {
+		return nil
+	}
+
+	var schemas []Schema
+	for _, versions := range m.subjects {
+		schemas = append(schemas, versions...)
+	}
+	slices.SortFunc(schemas, func(a, b Schema) int {
+		if a.ID != b.ID {
+			return a.ID - b.ID
+		}
+		return a.Version - b.Version
+	})
+
+	data, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close() //nolint:errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.path)
+}
+
+func normalize(s Schema) Schema {
+	if s.Type == "" {
+		s.Type = TypeJSON
+	}
+	return s
+}
+
+// same reports whether a and b are the same schema. JSON schemas are compared
+// by value, so formatting does not matter.
+func same(a, b Schema) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Type == TypeJSON {
+		var x, y any
+		if json.Unmarshal([]byte(a.Schema), &x) == nil && json.Unmarshal([]byte(b.Schema), &y) == nil {
+			return reflect.DeepEqual(x, y)
+		}
+	}
+	return a.Schema == b.Schema
+}
+
+// compatible checks that events written with old can be read with s.
+func compatible(old, s Schema) error {
+	if old.Type != s.Type {
+		return fmt.Errorf("schema type changed from %s to %s", old.Type, s.Type)
+	}
+	if s.Type != TypeJSON {
+		return nil
+	}
+
+	var o, n map[string]any
+	if err := json.Unmarshal([]byte(old.Schema), &o); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(s.Schema), &n); err != nil {
+		return err
+	}
+	return compatibleObject(o, n, "")
+}
+
+// compatibleObject checks the properties of an object schema: a property may not
+// become required, change its type, or disappear when additional properties
+// are not allowed.
+func compatibleObject(old, s map[string]any, path string) error {
+	oldProps, _ := old["properties"].(map[string]any)
+	props, _ := s["properties"].(map[string]any)
+
+	oldRequired := stringsOf(old["required"])
+	for _, name := range stringsOf(s["required"]) {
+		if !slices.Contains(oldRequired, name) {
+			return fmt.Errorf("property %s%s became required", path, name)
+		}
+	}
+
+	for name, op := range oldProps {
+		p, ok := props[name].(map[string]any)
+		if !ok {
+			if s["additionalProperties"] == false {
+				return fmt.Errorf("property %s%s was removed", path, name)
+			}
+			continue
+		}
+		o, _ := op.(map[string]any)
+		if t, ok := p["type"]; ok && o["type"] != nil && !reflect.DeepEqual(o["type"], t) {
+			return fmt.Errorf("property %s%s changed type from %v to %v", path, name, o["type"], t)
+		}
+		if err := compatibleObject(o, p, path+name+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stringsOf(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		if str, ok := s.(string); ok {
+			out = append(out, str)
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 11.40
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/schema
+ */
+
+package schema
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+const deviceSchema = `{
+	"type": "object",
+	"properties": {"id": {"type": "string"}, "jid": {"type": "string"}},
+	"required": ["id", "jid"]
+}`
+
+func TestMemory_Compatibility(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	id, err := m.Register(ctx, "devices-value", Schema{Schema: deviceSchema})
+	if err != nil || id != 1 {
+		t.Fatalf("Expected schema 1, got %d (%v)", id, err)
+	}
+	again, err := m.Register(ctx, "devices-value", Schema{Type: TypeJSON, Schema: `{"required":["id","jid"],"type":"object","properties":{"jid":{"type":"string"},"id":{"type":"string"}}}`})
+	if err != nil || again != id {
+		t.Errorf("Expected the same schema to keep id %d, got %d (%v)", id, again, err)
+	}
+
+	tests := []struct {
+		name   string
+		schema string
+		ok     bool
+	}{
+		{"optional property added", `{"type":"object","properties":{"id":{"type":"string"},"jid":{"type":"string"},"name":{"type":"string"}},"required":["id","jid"]}`, true},
+		{"property renamed", `{"type":"object","properties":{"id":{"type":"string"},"device_jid":{"type":"string"}},"required":["id","device_jid"]}`, false},
+		{"type changed", `{"type":"object","properties":{"id":{"type":"integer"},"jid":{"type":"string"}},"required":["id","jid"]}`, false},
+		{"property removed from closed object", `{"type":"object","properties":{"id":{"type":"string"}},"required":["id"],"additionalProperties":false}`, false},
+	}
+	for _, tt := range tests {
+		ok, err := m.Compatible(ctx, "devices-value", Schema{Schema: tt.schema})
+		if err != nil || ok != tt.ok {
+			t.Errorf("%s: expected compatible %v, got %v (%v)", tt.name, tt.ok, ok, err)
+		}
+	}
+
+	if _, err := m.Register(ctx, "devices-value", Schema{Schema: tests[1].schema}); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible, got %v", err)
+	}
+	if ok, _ := m.Compatible(ctx, "sessions-value", Schema{Schema: tests[1].schema}); !ok {
+		t.Error("Expected any schema to be compatible with an empty subject")
+	}
+	if other, _ := m.Register(ctx, "sessions-value", Schema{Schema: deviceSchema}); other != id {
+		t.Errorf("Expected the schema to keep id %d under another subject, got %d", id, other)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "schemas.json")
+
+	m, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("Failed to open registry: %v", err)
+	}
+	if _, err := m.Register(ctx, "devices-value", Schema{Schema: deviceSchema}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	v2 := `{"type":"object","properties":{"id":{"type":"string"},"jid":{"type":"string"},"name":{"type":"string"}},"required":["id","jid"]}`
+	if _, err := m.Register(ctx, "devices-value", Schema{Schema: v2}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+
+	reopened, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen registry: %v", err)
+	}
+	latest, err := reopened.Latest(ctx, "devices-value")
+	if err != nil || latest.ID != 2 || latest.Version != 2 || latest.Subject != "devices-value" {
+		t.Errorf("Expected version 2 with id 2, got %+v (%v)", latest, err)
+	}
+	if s, err := reopened.SchemaByID(ctx, 1); err != nil || !same(s, Schema{Type: TypeJSON, Schema: deviceSchema}) {
+		t.Errorf("Expected schema 1 to be kept, got %+v (%v)", s, err)
+	}
+	if _, err := reopened.Latest(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 09.10
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/schema
+ */
+
+package schema
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+)
+
+// Type is the schemaType of a schema in the Confluent schema registry API.
+type Type string
+
+const (
+	TypeJSON     Type = "JSON"
+	TypeProtobuf Type = "PROTOBUF"
+)
+
+var (
+	ErrNotFound     = errors.New("schema: not found")
+	ErrIncompatible = errors.New("schema: incompatible with the latest version of the subject")
+	// ErrUnavailable reports a schema that could not be fetched because the
+	// registry failed or timed out. Decoding the message again later may succeed.
+	ErrUnavailable = errors.New("schema: registry unavailable")
+)
+
+// Schema is one version of a subject. ID identifies the schema text across
+// subjects and is what payloads carry.
+type Schema struct {
+	ID      int    `json:"id,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Type    Type   `json:"schemaType,omitempty"`
+	Schema  string `json:"schema"`
+}
+
+// Registry stores schemas by subject, usually named "<topic>-value".
+type Registry interface {
+	// Register adds s as the next version of subject and returns its ID. A schema
+	// the subject already has returns the existing ID. It fails with
+	// ErrIncompatible when s breaks the latest version.
+	Register(ctx context.Context, subject string, s Schema) (int, error)
+	// Lookup returns the version of subject whose schema is s.
+	Lookup(ctx context.Context, subject string, s Schema) (Schema, error)
+	Latest(ctx context.Context, subject string) (Schema, error)
+	SchemaByID(ctx context.Context, id int) (Schema, error)
+	// Compatible reports whether s can be registered to subject: consumers
+	// using s must be able to read events written with the latest version. Any
+	// schema is compatible with a subject that has none.
+	Compatible(ctx context.Context, subject string, s Schema) (bool, error)
+}
+
+const magicByte = 0
+
+// Frame prefixes payload with the wire format header: a zero magic byte and the
+// big-endian schema ID.
+func Frame(id int, payload []byte) []byte {
+	data := make([]byte, 5, 5+len(payload))
+	data[0] = magicByte
+	binary.BigEndian.PutUint32(data[1:], uint32(id))
+	return append(data, payload...)
+}
+
+// Unframe returns the schema ID and payload of a message in the wire format.
+func Unframe(data []byte) (int, []byte, error) {
+	if len(data) < 5 || data[0] != magicByte {
+		return 0, nil, errors.New("schema: payload is not in the schema registry wire format")
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
+
+// Protobuf payloads carry the index path of their message type within the schema
+// after the ID. Only the first message type, sent as a single zero, is produced.
+var firstMessage = []byte{0}
+
+func skipMessageIndexes(payload []byte) ([]byte, error) {
+	n, size := binary.Varint(payload)
+	if size <= 0 {
+		return nil, errors.New("schema: invalid protobuf message indexes")
+	}
+	payload = payload[size:]
+	for range n {
+		if _, size = binary.Varint(payload); size <= 0 {
+			return nil, errors.New("schema: invalid protobuf message indexes")
+		}
+		payload = payload[size:]
+	}
+	return payload, nil
+}
@@ -0,0 +1,254 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 11.05
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/schema
+ */
+
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PakaiWA/pakaiwa-platform/messaging/codec"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Content types of messages written by a Serializer.
+const (
+	ContentTypeJSON     = "application/schema-registry+json"
+	ContentTypeProtobuf = "application/schema-registry+protobuf"
+)
+
+// SerializerConfig configures a Serializer.
+type SerializerConfig struct {
+	Registry Registry
+	// Subject and Schema describe the events a producer writes. Leave Subject
+	// empty for a Serializer that only decodes; Schema.Type still selects its
+	// content type, default TypeJSON.
+	Subject string
+	Schema  Schema
+	// AutoRegister registers Schema when the subject does not have it yet.
+	// Otherwise it must be registered beforehand, e.g. by CI.
+	AutoRegister bool
+	// Timeout bounds fetching a schema the first time its ID is decoded,
+	// default 10s.
+	Timeout time.Duration
+}
+
+// Serializer is a codec.Codec for payloads in the schema registry wire format.
+// JSON events are validated against their JSON Schema when encoded and decoded.
+// Consumers decode by content type, so register a Serializer of each type they
+// read with codec.Register.
+type Serializer struct {
+	cfg SerializerConfig
+	id  int
+	own *jsonschema.Schema
+
+	mu      sync.Mutex
+	byID    map[int]Schema
+	schemas map[int]*jsonschema.Schema
+}
+
+// NewSerializer checks that Schema is compatible with the latest version of
+// Subject, registering it when AutoRegister is set, and resolves its ID. Run it
+// on startup, so an incompatible producer fails before it sends anything.
+func NewSerializer(ctx context.Context, cfg SerializerConfig) (*Serializer, error) {
+	if cfg.Registry == nil {
+		return nil, errors.New("schema: serializer needs a registry")
+	}
+	cfg.Schema = normalize(cfg.Schema)
+	if cfg.Schema.Type != TypeJSON && cfg.Schema.Type != TypeProtobuf {
+		return nil, fmt.Errorf("schema: unsupported schema type %s", cfg.Schema.Type)
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	s := &Serializer{cfg: cfg, byID: make(map[int]Schema), schemas: make(map[int]*jsonschema.Schema)}
+	if cfg.Subject == "" {
+		return s, nil
+	}
+
+	ok, err := cfg.Registry.Compatible(ctx, cfg.Subject, cfg.Schema)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrIncompatible, cfg.Subject)
+	}
+
+	if cfg.AutoRegister {
+		s.id, err = cfg.Registry.Register(ctx, cfg.Subject, cfg.Schema)
+	} else {
+		var registered Schema
+		registered, err = cfg.Registry.Lookup(ctx, cfg.Subject, cfg.Schema)
+		s.id = registered.ID
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schema: resolving %s: %w", cfg.Subject, err)
+	}
+
+	if cfg.Schema.Type == TypeJSON {
+		if s.own, err = compile(s.id, cfg.Schema.Schema); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// ID returns the schema ID written with every payload, 0 for a Serializer that
+// only decodes.
+func (s *Serializer) ID() int { return s.id }
+
+func (s *Serializer) ContentType() string {
+	if s.cfg.Schema.Type == TypeProtobuf {
+		return ContentTypeProtobuf
+	}
+	return ContentTypeJSON
+}
+
+func (s *Serializer) Marshal(v any) ([]byte, error) {
+	if s.id == 0 {
+		return nil, errors.New("schema: serializer has no subject to encode for")
+	}
+
+	if s.cfg.Schema.Type == TypeProtobuf {
+		payload, err := codec.Protobuf{}.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return Frame(s.id, append(firstMessage, payload...)), nil
+	}
+
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(s.own, s.id, payload); err != nil {
+		return nil, err
+	}
+	return Frame(s.id, payload), nil
+}
+
+// Unmarshal decodes data with the schema its ID refers to, which is fetched from
+// the registry the first time it is seen. When the registry cannot be reached the
+// error wraps ErrUnavailable.
+func (s *Serializer) Unmarshal(data []byte, v any) error {
+	id, payload, err := Unframe(data)
+	if err != nil {
+		return err
+	}
+	sch, err := s.schemaByID(id)
+	if err != nil {
+		return err
+	}
+
+	switch sch.Type {
+	case TypeProtobuf:
+		if payload, err = skipMessageIndexes(payload); err != nil {
+			return err
+		}
+		return codec.Protobuf{}.Unmarshal(payload, v)
+	case TypeJSON:
+		compiled, err := s.compiled(id, sch.Schema)
+		if err != nil {
+			return err
+		}
+		if err := validate(compiled, id, payload); err != nil {
+			return err
+		}
+		return json.Unmarshal(payload, v)
+	default:
+		return fmt.Errorf("schema: unsupported schema type %s", sch.Type)
+	}
+}
+
+// schemaByID returns schema id, fetching it from the registry the first time id
+// is seen.
+func (s *Serializer) schemaByID(id int) (Schema, error) {
+	s.mu.Lock()
+	sch, ok := s.byID[id]
+	s.mu.Unlock()
+	if ok {
+		return sch, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	sch, err := s.cfg.Registry.SchemaByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return Schema{}, fmt.Errorf("schema: fetching schema %d: %w", id, err)
+	}
+	if err != nil {
+		return Schema{}, fmt.Errorf("%w: fetching schema %d: %w", ErrUnavailable, id, err)
+	}
+	sch = normalize(sch)
+
+	s.mu.Lock()
+	s.byID[id] = sch
+	s.mu.Unlock()
+	return sch, nil
+}
+
+// compiled returns JSON schema id, compiling text the first time id is seen.
+func (s *Serializer) compiled(id int, text string) (*jsonschema.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sch, ok := s.schemas[id]; ok {
+		return sch, nil
+	}
+	sch, err := compile(id, text)
+	if err != nil {
+		return nil, err
+	}
+	s.schemas[id] = sch
+	return sch, nil
+}
+
+func validate(sch *jsonschema.Schema, id int, payload []byte) error {
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if err := sch.Validate(inst); err != nil {
+		return fmt.Errorf("schema: payload does not match schema %d: %w", id, err)
+	}
+	return nil
+}
+
+func compile(id int, text string) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("schema: parsing schema %d: %w", id, err)
+	}
+
+	loc := "registry:///schemas/" + strconv.Itoa(id)
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(loc, doc); err != nil {
+		return nil, err
+	}
+	sch, err := c.Compile(loc)
+	if err != nil {
+		return nil, fmt.Errorf("schema: compiling schema %d: %w", id, err)
+	}
+	return sch, nil
+}
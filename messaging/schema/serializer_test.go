@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 12.30
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/messaging/schema
+ */
+
+package schema
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type deviceEvent struct {
+	ID  string `json:"id"`
+	JID string `json:"jid,omitempty"`
+}
+
+func TestFrame(t *testing.T) {
+	data := Frame(258, []byte("{}"))
+	if string(data[:5]) != "\x00\x00\x00\x01\x02" {
+		t.Errorf("Expected magic byte and big-endian id, got %x", data[:5])
+	}
+
+	id, payload, err := Unframe(data)
+	if err != nil || id != 258 || string(payload) != "{}" {
+		t.Errorf("Expected id 258 and payload {}, got %d %s (%v)", id, payload, err)
+	}
+	if _, _, err := Unframe([]byte("{}")); err == nil {
+		t.Error("Expected error for a payload without the wire format header")
+	}
+}
+
+func TestSerializer_JSON(t *testing.T) {
+	ctx := context.Background()
+	reg := NewMemory()
+
+	s, err := NewSerializer(ctx, SerializerConfig{
+		Registry:     reg,
+		Subject:      "devices-value",
+		Schema:       Schema{Schema: deviceSchema},
+		AutoRegister: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create serializer: %v", err)
+	}
+	if s.ContentType() != ContentTypeJSON || s.ID() != 1 {
+		t.Errorf("Expected JSON serializer for schema 1, got %s %d", s.ContentType(), s.ID())
+	}
+
+	data, err := s.Marshal(deviceEvent{ID: "1", JID: "a"})
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if id, _, _ := Unframe(data); id != 1 {
+		t.Errorf("Expected payload framed with schema 1, got %d", id)
+	}
+	if _, err := s.Marshal(deviceEvent{ID: "1"}); err == nil {
+		t.Error("Expected error for an event missing a required property")
+	}
+
+	reader, err := NewSerializer(ctx, SerializerConfig{Registry: reg})
+	if err != nil {
+		t.Fatalf("Failed to create deserializer: %v", err)
+	}
+	var got deviceEvent
+	if err := reader.Unmarshal(data, &got); err != nil || got != (deviceEvent{ID: "1", JID: "a"}) {
+		t.Errorf("Expected the event back, got %+v (%v)", got, err)
+	}
+	if _, err := reader.Marshal(got); err == nil {
+		t.Error("Expected a serializer without subject to refuse to encode")
+	}
+
+	invalid := Frame(1, []byte(`{"id":"1"}`))
+	if err := reader.Unmarshal(invalid, &got); err == nil {
+		t.Error("Expected error for a payload that does not match its schema")
+	}
+}
+
+// countingRegistry counts the schemas fetched by ID.
+type countingRegistry struct {
+	*Memory
+	byID int
+}
+
+func (r *countingRegistry) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	r.byID++
+	return r.Memory.SchemaByID(ctx, id)
+}
+
+func TestSerializer_CachesSchemaByID(t *testing.T) {
+	ctx := context.Background()
+	reg := &countingRegistry{Memory: NewMemory()}
+
+	s, err := NewSerializer(ctx, SerializerConfig{Registry: reg, Subject: "devices-value", Schema: Schema{Schema: deviceSchema}, AutoRegister: true})
+	if err != nil {
+		t.Fatalf("Failed to create serializer: %v", err)
+	}
+	data, err := s.Marshal(deviceEvent{ID: "1", JID: "a"})
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	for range 3 {
+		var got deviceEvent
+		if err := s.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+	}
+	if reg.byID != 1 {
+		t.Errorf("Expected the schema to be fetched once, got %d", reg.byID)
+	}
+}
+
+// downRegistry fails every fetch by ID, as a registry that is unreachable.
+type downRegistry struct {
+	*Memory
+}
+
+func (downRegistry) SchemaByID(context.Context, int) (Schema, error) {
+	return Schema{}, errors.New("connection refused")
+}
+
+func TestSerializer_Unavailable(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := NewSerializer(ctx, SerializerConfig{Registry: downRegistry{NewMemory()}})
+	if err != nil {
+		t.Fatalf("Failed to create serializer: %v", err)
+	}
+	var got deviceEvent
+	if err := s.Unmarshal(Frame(1, []byte(`{"id":"1"}`)), &got); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable for a failed fetch, got %v", err)
+	}
+
+	s, err = NewSerializer(ctx, SerializerConfig{Registry: NewMemory()})
+	if err != nil {
+		t.Fatalf("Failed to create serializer: %v", err)
+	}
+	err = s.Unmarshal(Frame(7, []byte(`{"id":"1"}`)), &got)
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrNotFound only for an unknown schema, got %v", err)
+	}
+}
+
+func TestSerializer_Startup(t *testing.T) {
+	ctx := context.Background()
+	reg := NewMemory()
+
+	cfg := SerializerConfig{Registry: reg, Subject: "devices-value", Schema: Schema{Schema: deviceSchema}}
+	if _, err := NewSerializer(ctx, cfg); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected unregistered schema to fail without AutoRegister, got %v", err)
+	}
+	if _, err := reg.Register(ctx, "devices-value", cfg.Schema); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if _, err := NewSerializer(ctx, cfg); err != nil {
+		t.Errorf("Expected registered schema to be resolved, got %v", err)
+	}
+
+	cfg.Schema = Schema{Schema: `{"type":"object","properties":{"device_jid":{"type":"string"}},"required":["device_jid"]}`}
+	cfg.AutoRegister = true
+	if _, err := NewSerializer(ctx, cfg); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible on startup, got %v", err)
+	}
+}
+
+func TestSerializer_Protobuf(t *testing.T) {
+	ctx := context.Background()
+	reg := NewMemory()
+
+	s, err := NewSerializer(ctx, SerializerConfig{
+		Registry:     reg,
+		Subject:      "names-value",
+		Schema:       Schema{Type: TypeProtobuf, Schema: `syntax = "proto3"; message StringValue { string value = 1; }`},
+		AutoRegister: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create serializer: %v", err)
+	}
+	if s.ContentType() != ContentTypeProtobuf {
+		t.Errorf("Expected protobuf content type, got %s", s.ContentType())
+	}
+
+	data, err := s.Marshal(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	var got *wrapperspb.StringValue
+	if err := s.Unmarshal(data, &got); err != nil || got.GetValue() != "hello" {
+		t.Errorf("Expected hello back, got %q (%v)", got.GetValue(), err)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 15.20
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/observability/metrics
+ */
+
+package metrics
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	KafkaMessagesProcessed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consumer_messages_processed_total",
+			Help: "Kafka messages handled successfully.",
+		},
+		[]string{"group", "topic"},
+	)
+
+	KafkaMessagesFailed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consumer_messages_failed_total",
+			Help: "Kafka messages that failed to decode or whose handler returned an error.",
+		},
+		[]string{"group", "topic"},
+	)
+
+	KafkaHandlerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kafka_consumer_handler_duration_seconds",
+			Help:    "Kafka message handler duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"group", "topic"},
+	)
+
+	KafkaRebalances = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consumer_rebalances_total",
+			Help: "Kafka partition assignments and revocations.",
+		},
+		[]string{"group", "type"},
+	)
+
+	KafkaConsumerLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_lag",
+			Help: "Messages between the high watermark and the committed offset of an assigned partition.",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+
+	KafkaProducerDelivered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_producer_messages_delivered_total",
+			Help: "Kafka messages acknowledged by the broker.",
+		},
+		[]string{"topic"},
+	)
+
+	KafkaProducerFailed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_producer_messages_failed_total",
+			Help: "Kafka messages whose delivery failed.",
+		},
+		[]string{"topic"},
+	)
+
+	KafkaProducerDeliveryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kafka_producer_delivery_duration_seconds",
+			Help:    "Time from Send to the broker's acknowledgement.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"topic"},
+	)
+
+	KafkaProducerQueue = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_producer_queue_messages",
+			Help: "Messages and requests waiting to be delivered by a Kafka producer.",
+		},
+		[]string{"client"},
+	)
+
+	KafkaClientQueueMessages = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_client_queue_messages",
+			Help: "Messages waiting in the librdkafka queues of a client.",
+		},
+		[]string{"client"},
+	)
+
+	KafkaClientReplyQueue = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_client_reply_queue",
+			Help: "Events waiting to be polled from a librdkafka client.",
+		},
+		[]string{"client"},
+	)
+
+	KafkaBrokerRTT = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_broker_rtt_seconds",
+			Help: "Average broker round-trip time seen by a librdkafka client.",
+		},
+		[]string{"client", "broker"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(KafkaMessagesProcessed)
+	prometheus.MustRegister(KafkaMessagesFailed)
+	prometheus.MustRegister(KafkaHandlerDuration)
+	prometheus.MustRegister(KafkaRebalances)
+	prometheus.MustRegister(KafkaConsumerLag)
+	prometheus.MustRegister(KafkaProducerDelivered)
+	prometheus.MustRegister(KafkaProducerFailed)
+	prometheus.MustRegister(KafkaProducerDeliveryDuration)
+	prometheus.MustRegister(KafkaProducerQueue)
+	prometheus.MustRegister(KafkaClientQueueMessages)
+	prometheus.MustRegister(KafkaClientReplyQueue)
+	prometheus.MustRegister(KafkaBrokerRTT)
+}
+
+// kafkaStats is the part of the librdkafka statistics JSON exported as metrics.
+// See https://github.com/confluentinc/librdkafka/blob/master/STATISTICS.md.
+type kafkaStats struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	MsgCnt  int64  `json:"msg_cnt"`
+	ReplyQ  int64  `json:"replyq"`
+	Brokers map[string]struct {
+		Name   string `json:"name"`
+		NodeID int32  `json:"nodeid"`
+		RTT    struct {
+			Avg int64 `json:"avg"` // microseconds
+		} `json:"rtt"`
+	} `json:"brokers"`
+	Topics map[string]struct {
+		Topic      string `json:"topic"`
+		Partitions map[string]struct {
+			Partition       int32  `json:"partition"`
+			FetchState      string `json:"fetch_state"`
+			HiOffset        int64  `json:"hi_offset"`
+			CommittedOffset int64  `json:"committed_offset"`
+		} `json:"partitions"`
+	} `json:"topics"`
+}
+
+// ObserveKafkaStats records a librdkafka statistics event, emitted every
+// statistics.interval.ms, as metrics. For consumers group labels the lag of
+// every partition that is being fetched.
+func ObserveKafkaStats(group, data string) error {
+	var s kafkaStats
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return err
+	}
+
+	KafkaClientQueueMessages.WithLabelValues(s.Name).Set(float64(s.MsgCnt))
+	KafkaClientReplyQueue.WithLabelValues(s.Name).Set(float64(s.ReplyQ))
+
+	for _, b := range s.Brokers {
+		if b.NodeID < 0 {
+			continue // bootstrap and internal brokers
+		}
+		KafkaBrokerRTT.WithLabelValues(s.Name, b.Name).Set(float64(b.RTT.Avg) / 1e6)
+	}
+
+	if s.Type != "consumer" {
+		return nil
+	}
+	for _, t := range s.Topics {
+		for _, p := range t.Partitions {
+			if p.Partition < 0 || p.FetchState == "none" || p.HiOffset < 0 || p.CommittedOffset < 0 {
+				continue
+			}
+			KafkaConsumerLag.WithLabelValues(group, t.Topic, strconv.Itoa(int(p.Partition))).
+				Set(float64(max(p.HiOffset-p.CommittedOffset, 0)))
+		}
+	}
+	return nil
+}
+
+// ForgetKafkaPartition drops the lag of a partition the consumer no longer owns.
+func ForgetKafkaPartition(group, topic string, partition int32) {
+	KafkaConsumerLag.DeleteLabelValues(group, topic, strconv.Itoa(int(partition)))
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 15.50
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/observability/metrics
+ */
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const consumerStats = `{
+  "name": "rdkafka#consumer-1",
+  "type": "consumer",
+  "msg_cnt": 12,
+  "replyq": 3,
+  "brokers": {
+    "GroupCoordinator": {"name": "GroupCoordinator", "nodeid": -1, "rtt": {"avg": 0}},
+    "localhost:9092/1": {"name": "localhost:9092/1", "nodeid": 1, "rtt": {"avg": 2500}}
+  },
+  "topics": {
+    "devices": {
+      "topic": "devices",
+      "partitions": {
+        "0": {"partition": 0, "fetch_state": "active", "hi_offset": 120, "committed_offset": 100},
+        "1": {"partition": 1, "fetch_state": "none", "hi_offset": 50, "committed_offset": 10},
+        "2": {"partition": 2, "fetch_state": "active", "hi_offset": 7, "committed_offset": -1001},
+        "-1": {"partition": -1, "fetch_state": "none", "hi_offset": -1001, "committed_offset": -1001}
+      }
+    }
+  }
+}`
+
+func TestObserveKafkaStats(t *testing.T) {
+	if err := ObserveKafkaStats("test", consumerStats); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := testutil.ToFloat64(KafkaConsumerLag.WithLabelValues("test", "devices", "0")); v != 20 {
+		t.Errorf("Expected lag 20, got %v", v)
+	}
+	if n := testutil.CollectAndCount(KafkaConsumerLag); n != 1 {
+		t.Errorf("Expected lag only for the fetched partition with a committed offset, got %d series", n)
+	}
+	if v := testutil.ToFloat64(KafkaClientQueueMessages.WithLabelValues("rdkafka#consumer-1")); v != 12 {
+		t.Errorf("Expected 12 queued messages, got %v", v)
+	}
+	if v := testutil.ToFloat64(KafkaBrokerRTT.WithLabelValues("rdkafka#consumer-1", "localhost:9092/1")); v != 0.0025 {
+		t.Errorf("Expected RTT 0.0025s, got %v", v)
+	}
+	if n := testutil.CollectAndCount(KafkaBrokerRTT); n != 1 {
+		t.Errorf("Expected RTT only for real brokers, got %d series", n)
+	}
+
+	ForgetKafkaPartition("test", "devices", 0)
+	if n := testutil.CollectAndCount(KafkaConsumerLag); n != 0 {
+		t.Errorf("Expected lag to be removed, got %d series", n)
+	}
+
+	if err := ObserveKafkaStats("test", "{not json"); err == nil {
+		t.Error("Expected error for invalid statistics")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 09.15
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/pagination
+ */
+
+// Package pagination holds the page request and cursor types shared by the HTTP
+// layer, which parses them, and the database layer, which executes them.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor reports a cursor that was not issued by EncodeCursor. It
+// describes bad client input and maps to HTTP 400.
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+// FilterOp is a comparison allowed in a Filter.
+type FilterOp string
+
+// Supported filter operators.
+const (
+	OpEq    FilterOp = "eq"
+	OpNe    FilterOp = "ne"
+	OpLt    FilterOp = "lt"
+	OpLte   FilterOp = "lte"
+	OpGt    FilterOp = "gt"
+	OpGte   FilterOp = "gte"
+	OpIn    FilterOp = "in"
+	OpLike  FilterOp = "like" // prefix match
+	OpExist FilterOp = "exists"
+)
+
+// SortField orders results by a sort key.
+type SortField struct {
+	Key  string
+	Desc bool
+}
+
+// Filter restricts results on a filter key. Value is the raw client input; for
+// OpIn it is a comma-separated list.
+type Filter struct {
+	Key   string
+	Op    FilterOp
+	Value string
+}
+
+// PageRequest is a client's request for one page of a list endpoint.
+type PageRequest struct {
+	Limit   int
+	Cursor  string
+	Sort    []SortField
+	Filters []Filter
+}
+
+// Cursor is the position after the last row of a page: the sort order it was
+// issued for and the row's sort values.
+type Cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// EncodeCursor returns the opaque, URL-safe form of c.
+func EncodeCursor(c Cursor) (string, error) {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor reverses EncodeCursor.
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
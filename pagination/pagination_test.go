@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 21.40
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/pagination
+ */
+
+package pagination
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	s, err := EncodeCursor(Cursor{Sort: "-created_at,-id", Values: []string{"2026-10-18T10:00:00Z", "99"}})
+	if err != nil {
+		t.Fatalf("Failed to encode cursor: %v", err)
+	}
+
+	c, err := DecodeCursor(s)
+	if err != nil {
+		t.Fatalf("Failed to decode cursor: %v", err)
+	}
+	if c.Sort != "-created_at,-id" || !slices.Equal(c.Values, []string{"2026-10-18T10:00:00Z", "99"}) {
+		t.Errorf("Unexpected cursor %+v", c)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"!!!", "bm90IGpzb24"} {
+		if _, err := DecodeCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor for %q, got %v", s, err)
+		}
+	}
+}
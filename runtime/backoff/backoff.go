@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 10.02
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/runtime/backoff
+ */
+
+// Package backoff provides exponential backoff with jitter for retry loops.
+package backoff
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy describes an exponential backoff. Unset fields take their defaults, so
+// the zero value behaves like Default(). Set Jitter to NoJitter for exact delays.
+type Policy struct {
+	Initial    time.Duration // delay before the first retry, default 500ms
+	Max        time.Duration // upper bound for a single delay, default 30s
+	Multiplier float64       // growth factor per attempt, default 2
+	Jitter     float64       // random spread in (0,1] applied to each delay, default 0.2
+}
+
+// NoJitter disables jitter when set as Policy.Jitter.
+const NoJitter = -1.0
+
+// Default returns the platform's default backoff policy.
+func Default() Policy {
+	return Policy{
+		Initial:    500 * time.Millisecond,
+		Max:        30 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// Delay returns the wait before retry number attempt (starting at 1).
+func (p Policy) Delay(attempt int) time.Duration {
+	def := Default()
+	if p.Initial <= 0 {
+		p.Initial = def.Initial
+	}
+	if p.Max <= 0 {
+		p.Max = def.Max
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = def.Multiplier
+	}
+	switch {
+	case p.Jitter < 0:
+		p.Jitter = 0
+	case p.Jitter == 0 || p.Jitter > 1:
+		p.Jitter = def.Jitter
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	d := float64(p.Initial) * math.Pow(p.Multiplier, float64(attempt-1))
+	if d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (rand.Float64()*2 - 1) //nolint:gosec
+	}
+
+	return time.Duration(d)
+}
+
+// Sleep waits for d or until ctx is done, returning ctx.Err() in the latter case.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2026 KAnggara
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * See <https://www.gnu.org/licenses/gpl-3.0.html>.
+ *
+ * @author KAnggara on Sunday 18/10/2026 10.11
+ * @project pp
+ * https://github.com/PakaiWA/pakaiwa-platform/tree/main/runtime/backoff
+ */
+
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2, Jitter: NoJitter}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := p.Delay(tt.attempt); got != tt.expected {
+			t.Errorf("Delay(%d): expected %v, got %v", tt.attempt, tt.expected, got)
+		}
+	}
+}
+
+func TestPolicy_DelayJitter(t *testing.T) {
+	p := Policy{Initial: time.Second, Max: time.Minute, Multiplier: 2, Jitter: 0.5}
+
+	for range 100 {
+		d := p.Delay(1)
+		if d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("Expected delay within jitter bounds, got %v", d)
+		}
+	}
+}
+
+func TestPolicy_ZeroValue(t *testing.T) {
+	var p Policy
+
+	jittered := false
+	for range 100 {
+		d := p.Delay(1)
+		if d < 400*time.Millisecond || d > 600*time.Millisecond {
+			t.Fatalf("Expected default initial delay around 500ms, got %v", d)
+		}
+		jittered = jittered || d != 500*time.Millisecond
+	}
+	if !jittered {
+		t.Error("Expected the zero value to apply the default jitter")
+	}
+}
+
+func TestPolicy_PartialKeepsDefaultJitter(t *testing.T) {
+	p := Policy{Initial: time.Second, Max: time.Minute}
+
+	jittered := false
+	for range 100 {
+		d := p.Delay(1)
+		if d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("Expected delay within the default jitter, got %v", d)
+		}
+		jittered = jittered || d != time.Second
+	}
+	if !jittered {
+		t.Error("Expected a Policy without Jitter to apply the default jitter")
+	}
+}
+
+func TestSleep_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Sleep(ctx, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if err := Sleep(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+}